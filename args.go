@@ -0,0 +1,166 @@
+package gomocket
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// isArgsMatch returns true either when nothing to compare or all args are equal.
+// Arguments created by sql.Named are compared with actual argument of the same name,
+// ArgMatcher decides itself if argument matches
+func (fr *FakeResponse) isArgsMatch(args []driver.NamedValue) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	for index, expected := range fr.ArgsAt {
+		if index < 0 || index >= len(args) || !isArgMatch(expected, index, args) {
+			return false
+		}
+	}
+	if fr.Args == nil {
+		return true
+	}
+	if len(fr.Args) != len(args) {
+		return false
+	}
+	for index, expected := range fr.Args {
+		if !isArgMatch(expected, index, args) {
+			return false
+		}
+	}
+	return true
+}
+
+// isArgMatch compares expected argument with actual one at the same position or with the same name
+func isArgMatch(expected interface{}, index int, args []driver.NamedValue) bool {
+	if named, ok := expected.(sql.NamedArg); ok {
+		for _, arg := range args {
+			if arg.Name == named.Name {
+				return isValueMatch(named.Value, arg.Value)
+			}
+		}
+		return false
+	}
+	return isValueMatch(expected, args[index].Value)
+}
+
+// isValueMatch uses matcher or compares values as is or after conversion of expected one to driver.Value,
+// so int(5) matches int64(5) received by driver
+func isValueMatch(expected, actual interface{}) bool {
+	if matcher, ok := expected.(ArgMatcher); ok {
+		return matcher.Match(actual)
+	}
+	if reflect.DeepEqual(expected, actual) {
+		return true
+	}
+	converted, err := driver.DefaultParameterConverter.ConvertValue(expected)
+	return err == nil && reflect.DeepEqual(converted, actual)
+}
+
+// describeArgs returns human readable list of arguments
+func describeArgs(args []interface{}) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			parts[i] = "@" + named.Name + "=" + describeArg(named.Value)
+		} else {
+			parts[i] = describeArg(arg)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// describeNamedValues returns human readable list of arguments received by driver
+func describeNamedValues(args []driver.NamedValue) string {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			values[i] = sql.Named(arg.Name, arg.Value)
+		} else {
+			values[i] = arg.Value
+		}
+	}
+	return describeArgs(values)
+}
+
+// describeArg returns human readable argument, matchers describe themselves
+func describeArg(arg interface{}) string {
+	if matcher, ok := arg.(ArgMatcher); ok {
+		return matcher.String()
+	}
+	return fmt.Sprintf("%#v", arg)
+}
+
+// ArgMatcher matches argument received by driver instead of comparing it with expected value
+type ArgMatcher interface {
+	Match(v driver.Value) bool
+	String() string // Description of matcher used in reports
+}
+
+// argMatcher is ArgMatcher based on function
+type argMatcher struct {
+	desc  string
+	match func(v driver.Value) bool
+}
+
+func (m argMatcher) Match(v driver.Value) bool {
+	return m.match(v)
+}
+
+func (m argMatcher) String() string {
+	return m.desc
+}
+
+// AnyArg matches any argument
+func AnyArg() ArgMatcher {
+	return argMatcher{"AnyArg()", func(driver.Value) bool { return true }}
+}
+
+// AnyOfType matches any argument of the same type as example after conversion to driver value,
+// e.g. AnyOfType(0) matches any int64 and AnyOfType(time.Time{}) matches any time
+func AnyOfType(example interface{}) ArgMatcher {
+	if v, err := driver.DefaultParameterConverter.ConvertValue(example); err == nil {
+		example = v
+	}
+	typ := reflect.TypeOf(example)
+	return argMatcher{fmt.Sprintf("AnyOfType(%v)", typ), func(v driver.Value) bool {
+		return reflect.TypeOf(v) == typ
+	}}
+}
+
+// ArgRegexp matches string or []byte argument against regular expression.
+// Expression is compiled once and panics if it is invalid
+func ArgRegexp(expr string) ArgMatcher {
+	re := regexp.MustCompile(expr)
+	return argMatcher{fmt.Sprintf("ArgRegexp(%q)", expr), func(v driver.Value) bool {
+		switch s := v.(type) {
+		case string:
+			return re.MatchString(s)
+		case []byte:
+			return re.Match(s)
+		}
+		return false
+	}}
+}
+
+// ArgPredicate matches argument if provided function returns true
+func ArgPredicate(f func(v driver.Value) bool) ArgMatcher {
+	return argMatcher{"ArgPredicate()", f}
+}
+
+// ArgTimeNear matches time argument which differs from provided one not more than delta.
+// Useful for arguments like time.Now()
+func ArgTimeNear(t time.Time, delta time.Duration) ArgMatcher {
+	return argMatcher{fmt.Sprintf("ArgTimeNear(%v, %v)", t, delta), func(v driver.Value) bool {
+		actual, ok := v.(time.Time)
+		if !ok {
+			return false
+		}
+		diff := actual.Sub(t)
+		return diff <= delta && diff >= -delta
+	}}
+}
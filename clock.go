@@ -0,0 +1,145 @@
+package gomocket
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock is source of time used by MockCatcher to emulate query delays
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer fires once after its duration passes on the Clock
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// randSource is implemented by clocks choosing random delays of queries
+type randSource interface {
+	Int63n(n int64) int64
+}
+
+// realClock is Clock based on time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// MockClock is Clock controlled from tests. Time moves forward only by Advance.
+// Random delays of queries are chosen by its own source, so they are reproducible
+type MockClock struct {
+	now    time.Time
+	timers []*mockTimer
+	rnd    *rand.Rand
+	mu     sync.Mutex
+	cond   *sync.Cond
+}
+
+// NewMockClock creates MockClock showing provided time. Random delays are seeded with it
+func NewMockClock(start time.Time) *MockClock {
+	c := &MockClock{now: start, rnd: rand.New(rand.NewSource(start.UnixNano()))}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// WithRand sets source of random delays of queries
+func (c *MockClock) WithRand(rnd *rand.Rand) *MockClock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rnd = rnd
+	return c
+}
+
+// Int63n returns random number in [0, n) used as random delay of query
+func (c *MockClock) Int63n(n int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rnd.Int63n(n)
+}
+
+// Now returns current time of the clock
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates timer firing when clock is advanced by d
+func (c *MockClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &mockTimer{clock: c, at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves clock forward and fires all timers which are due
+func (c *MockClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// BlockUntil waits until n timers are pending. Useful to advance clock
+// only when query is already waiting in another goroutine
+func (c *MockClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+type mockTimer struct {
+	clock *MockClock
+	at    time.Time
+	ch    chan time.Time
+}
+
+func (t *mockTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *mockTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -4,14 +4,12 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
-	"log"
-	"regexp"
-	"strings"
 	"sync"
 )
 
 // FakeConn implements connection
 type FakeConn struct {
+	id     int64 // Unique ID of connection used in journal
 	db     *FakeDB
 	currTx *FakeTx // Transaction pointer
 	mu     sync.Mutex
@@ -22,16 +20,46 @@ func (c *FakeConn) isBad() bool {
 	return false
 }
 
+// catcher returns MockCatcher bound to DSN of connection
+func (c *FakeConn) catcher() *MockCatcher {
+	if c.db == nil {
+		return Catcher
+	}
+	return catcherFor(c.db.name)
+}
+
+// ID returns unique identifier of connection
+func (c *FakeConn) ID() int64 {
+	return c.id
+}
+
 // Begin starts and returns a new transaction.
+//
+// Deprecated: Drivers should implement ConnBeginTx instead (or additionally).
 func (c *FakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts and returns a new transaction with provided isolation level and read-only flag
+func (c *FakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (_ driver.Tx, err error) {
+	st := &statement{start: c.catcher().clock().Now()}
+	defer func() { c.journal(JournalBegin, st, err) }()
 	if c.isBad() {
 		return nil, driver.ErrBadConn
 	}
 	if c.currTx != nil {
 		return nil, errors.New("already in a transaction")
 	}
-	c.currTx = &FakeTx{c: c}
-	return c.currTx, nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tx := newFakeTx(c, opts)
+	st.tx = tx
+	if err := c.catcher().txEvent(txBegin, tx); err != nil {
+		return nil, err
+	}
+	c.currTx = tx
+	return tx, nil
 }
 
 // Close terminates the db object
@@ -65,23 +93,24 @@ func (c *FakeConn) Prepare(query string) (driver.Stmt, error) {
 	panic("use Prepare")
 }
 
+// CheckNamedValue converts arguments to driver values like a real driver does, unsupported
+// arguments are rejected
+func (c *FakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = v
+	return nil
+}
+
 // PrepareContext returns a prepared statement, bound to this connection.
 // context is for the preparation of the statement,
 // it must not store the context within the statement itself.
 func (c *FakeConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
 	var firstStmt = &FakeStmt{q: query, connection: c}
-	// Checking how many placeholders do we have
-	if strings.Contains(query, "$1") {
-		r, err := regexp.Compile(`[$]\d+`)
-		if err != nil {
-			log.Fatalf(`Cant't compile regexp with err [%v]`, err)
-		}
-		firstStmt.placeholders = len(strings.Split(r.ReplaceAllString(query, `$$$`), "$$")) - 1 // Postgres notation
-	} else {
-		firstStmt.placeholders = len(strings.Split(query, "?")) - 1 // Postgres notation
-	}
-
-	queryParts := strings.Split(query, " ") // By First statement define the query type
-	firstStmt.command = strings.ToUpper(queryParts[0])
+	firstStmt.placeholders = countPlaceholders(query, c.catcher().backslashEscapes())
+	firstStmt.command = classifyCommand(query) // Type of statement to define the reply
+	c.journal(JournalPrepare, &statement{query: query, tx: c.currTx, start: c.catcher().clock().Now()}, nil)
 	return firstStmt, nil
 }
@@ -45,7 +45,7 @@ func (t *table) columnIndex(name string) int {
 
 // Open returns a new connection to the database.
 func (d *FakeDriver) Open(database string) (driver.Conn, error) {
-	return &FakeConn{db: d.getDB(database)}, nil
+	return &FakeConn{db: d.getDB(database), id: newConnID()}, nil
 }
 
 func (d *FakeDriver) getDB(name string) *FakeDB {
@@ -0,0 +1,96 @@
+package gomocket
+
+import (
+	"database/sql/driver"
+	"sync/atomic"
+	"time"
+)
+
+// Commands recorded in journal
+const (
+	JournalPrepare  = "PREPARE"
+	JournalExec     = "EXEC"
+	JournalQuery    = "QUERY"
+	JournalBegin    = txBegin
+	JournalCommit   = txCommit
+	JournalRollback = txRollback
+)
+
+// lastConnID is used to generate unique connection IDs
+var lastConnID int64
+
+// JournalEntry is a record of statement or transaction command sent to the driver
+type JournalEntry struct {
+	Command string              // One of PREPARE, EXEC, QUERY, BEGIN, COMMIT or ROLLBACK
+	Query   string              // SQL query, empty for transaction commands
+	Args    []driver.NamedValue // Args query executed with
+	Mock    *FakeResponse       // Mock which matched query, nil if none
+	TxID    int64               // ID of transaction, 0 if outside of transaction
+	ConnID  int64               // ID of connection
+	Start   time.Time           // Time command started by catcher clock
+	End     time.Time           // Time command finished by catcher clock
+	Err     error               // Error returned by driver
+}
+
+// record adds entry to journal
+func (mc *MockCatcher) record(entry JournalEntry) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.entries = append(mc.entries, entry)
+}
+
+// Journal returns all statements and transaction commands sent to the driver since last Reset
+func (mc *MockCatcher) Journal() []JournalEntry {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return append([]JournalEntry(nil), mc.entries...)
+}
+
+// JournalOf returns journal entries of provided commands
+// example: Catcher.JournalOf(JournalExec, JournalQuery)
+func (mc *MockCatcher) JournalOf(commands ...string) []JournalEntry {
+	var entries []JournalEntry
+	for _, entry := range mc.Journal() {
+		for _, command := range commands {
+			if entry.Command == command {
+				entries = append(entries, entry)
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// JournalFor returns journal entries of statements which were matched by mock
+func (mc *MockCatcher) JournalFor(mock *FakeResponse) []JournalEntry {
+	var entries []JournalEntry
+	for _, entry := range mc.Journal() {
+		if entry.Mock == mock {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// journal records command executed on connection
+func (c *FakeConn) journal(command string, st *statement, err error) {
+	mc := c.catcher()
+	entry := JournalEntry{
+		Command: command,
+		Query:   st.query,
+		Args:    st.args,
+		Mock:    st.mock,
+		ConnID:  c.id,
+		Start:   st.start,
+		End:     mc.clock().Now(),
+		Err:     err,
+	}
+	if st.tx != nil {
+		entry.TxID = st.tx.ID()
+	}
+	mc.record(entry)
+}
+
+func newConnID() int64 {
+	return atomic.AddInt64(&lastConnID, 1)
+}
@@ -0,0 +1,73 @@
+package gomocket
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"testing"
+)
+
+// Logger receives messages of MockCatcher about caught queries when Logging is on
+type Logger interface {
+	Logf(format string, args ...interface{})
+}
+
+// stdLogger writes messages with standard log package
+type stdLogger struct{}
+
+func (stdLogger) Logf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+// testLogger writes messages to log of the test
+type testLogger struct {
+	t testing.TB
+}
+
+func (l testLogger) Logf(format string, args ...interface{}) {
+	l.t.Helper()
+	l.t.Logf(format, args...)
+}
+
+// TestLogger returns Logger writing to log of provided test, so messages are attributed to it
+func TestLogger(t testing.TB) Logger {
+	return testLogger{t}
+}
+
+// slogLogger writes messages to structured logger
+type slogLogger struct {
+	l     *slog.Logger
+	level slog.Level
+}
+
+func (l slogLogger) Logf(format string, args ...interface{}) {
+	l.l.Log(context.Background(), l.level, fmt.Sprintf(format, args...))
+}
+
+// SlogLogger returns Logger writing to provided slog.Logger with provided level
+func SlogLogger(l *slog.Logger, level slog.Level) Logger {
+	return slogLogger{l, level}
+}
+
+// WithLogger sets logger for messages about caught queries and turns logging on
+// example: Catcher.Reset().WithLogger(TestLogger(t))
+func (mc *MockCatcher) WithLogger(l Logger) *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Logger = l
+	mc.Logging = true
+	return mc
+}
+
+// logf writes message if logging is on. Must be called under lock
+func (mc *MockCatcher) logf(format string, args ...interface{}) {
+	if !mc.Logging {
+		return
+	}
+	if mc.Logger == nil {
+		stdLogger{}.Logf(format, args...)
+		return
+	}
+	mc.Logger.Logf(format, args...)
+}
@@ -0,0 +1,57 @@
+package gomocket
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tightChars are characters which don't need whitespace around them after normalization
+const tightChars = "(),.=<>!;"
+
+// normalizeQuery canonicalizes SQL query to compare queries which differ only by formatting.
+// Whitespace is collapsed, identifier quotes are removed and everything out of string literals is lower-cased.
+// Content of string literals stays untouched.
+func normalizeQuery(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	pendingSpace := false
+	var prev rune
+
+	write := func(r rune) {
+		if pendingSpace && prev != 0 && !strings.ContainsRune(tightChars, prev) && !strings.ContainsRune(tightChars, r) {
+			b.WriteRune(' ')
+		}
+		pendingSpace = false
+		b.WriteRune(r)
+		prev = r
+	}
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'':
+			// Copy string literal as is, '' is an escaped quote
+			write(r)
+			for i++; i < len(runes); i++ {
+				b.WriteRune(runes[i])
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						b.WriteRune(runes[i])
+						continue
+					}
+					break
+				}
+			}
+			prev = '\''
+		case r == '"' || r == '`' || r == '[' || r == ']':
+			// Identifier quotes are dropped
+		case unicode.IsSpace(r):
+			pendingSpace = true
+		default:
+			write(unicode.ToLower(r))
+		}
+	}
+	return strings.TrimRight(b.String(), ";")
+}
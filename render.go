@@ -0,0 +1,110 @@
+package gomocket
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// renderQuery returns query with placeholders replaced by SQL literals of arguments.
+// Placeholders without corresponding argument stay as is
+func renderQuery(query string, args []driver.NamedValue, backslash bool) string {
+	if len(args) == 0 {
+		return query
+	}
+	tokens := tokenizeWith(query, backslash)
+	postgres := false
+	for _, t := range tokens {
+		if t.kind == tokenParam && t.text[0] == '$' {
+			postgres = true
+			break
+		}
+	}
+
+	runes := []rune(query)
+	var b strings.Builder
+	last, positional := 0, 0
+	for _, t := range tokens {
+		if t.kind != tokenParam || (postgres && t.text == "?") {
+			continue
+		}
+		var arg *driver.NamedValue
+		if t.text == "?" {
+			positional++
+			arg = argByOrdinal(args, positional)
+		} else {
+			arg = placeholderArg(t.text, args)
+		}
+		if arg == nil {
+			continue
+		}
+		b.WriteString(string(runes[last:t.pos]))
+		b.WriteString(sqlLiteral(arg.Value, postgres))
+		last = t.pos + len([]rune(t.text))
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String()
+}
+
+// placeholderArg finds argument for placeholder like $1, ?1, :name, :1, @name or @p1
+func placeholderArg(placeholder string, args []driver.NamedValue) *driver.NamedValue {
+	name := placeholder[1:]
+	if placeholder[0] == ':' || placeholder[0] == '@' {
+		for i := range args {
+			if args[i].Name != "" && strings.EqualFold(args[i].Name, name) {
+				return &args[i]
+			}
+		}
+		// SQL Server style @p1 refers argument by position
+		if placeholder[0] == '@' && len(name) > 1 && (name[0] == 'p' || name[0] == 'P') {
+			name = name[1:]
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		return argByOrdinal(args, n)
+	}
+	return nil
+}
+
+func argByOrdinal(args []driver.NamedValue, ordinal int) *driver.NamedValue {
+	for i := range args {
+		if args[i].Ordinal == ordinal {
+			return &args[i]
+		}
+	}
+	return nil
+}
+
+// sqlLiteral formats value as SQL literal, bytes are formatted according to dialect
+func sqlLiteral(v driver.Value, postgres bool) string {
+	switch value := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteString(value)
+	case []byte:
+		if postgres {
+			return `'\x` + hex.EncodeToString(value) + `'`
+		}
+		return "X'" + hex.EncodeToString(value) + "'"
+	case bool:
+		if value {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case time.Time:
+		return quoteString(value.Format("2006-01-02 15:04:05.999999999-07:00"))
+	}
+	return quoteString(fmt.Sprintf("%v", v))
+}
+
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
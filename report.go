@@ -0,0 +1,192 @@
+package gomocket
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// snippetLen is max length of query parts shown in reports
+const snippetLen = 30
+
+// unmatchedReport lists registered mocks starting from the closest one with reasons why they don't match statement.
+// Must be called under lock
+func (mc *MockCatcher) unmatchedReport(st *statement) string {
+	type candidate struct {
+		index   int
+		reasons []string
+		closest int // Length of query matched by pattern
+	}
+	candidates := make([]candidate, 0, len(mc.Mocks))
+	for i, resp := range mc.Mocks {
+		reasons, closest := resp.mismatches(st.queryFor(resp), st.args)
+		candidates = append(candidates, candidate{i, reasons, closest})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if len(candidates[i].reasons) != len(candidates[j].reasons) {
+			return len(candidates[i].reasons) < len(candidates[j].reasons)
+		}
+		return candidates[i].closest > candidates[j].closest
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "no mock matched query: %s", st.query)
+	if len(st.args) > 0 {
+		fmt.Fprintf(&b, "\n\targs: %s", describeNamedValues(st.args))
+	}
+	if st.rendered != "" && st.rendered != st.query {
+		fmt.Fprintf(&b, "\n\trendered: %s", st.rendered)
+	}
+	if len(candidates) == 0 {
+		b.WriteString("\n\tthere are no registered mocks")
+	}
+	for _, c := range candidates {
+		fmt.Fprintf(&b, "\n\tmock #%d %s:", c.index, mc.Mocks[c.index].describe())
+		for _, reason := range c.reasons {
+			b.WriteString("\n\t\t" + strings.Replace(reason, "\n", "\n\t\t", -1))
+		}
+	}
+	return b.String()
+}
+
+// mismatches explains why mock doesn't match query and args. Also returns how many characters
+// of query are matched by pattern to find the closest mock
+func (fr *FakeResponse) mismatches(query string, args []driver.NamedValue) ([]string, int) {
+	var reasons []string
+	fr.mu.Lock()
+	if fr.Once && fr.Triggered {
+		reasons = append(reasons, "already triggered once")
+	} else if fr.isExhausted() {
+		reasons = append(reasons, "all responses of sequence were used")
+	}
+	fr.mu.Unlock()
+
+	if !fr.isCommandMatch(query) {
+		reasons = append(reasons, fmt.Sprintf("statement type is %q, expected %q", classifyCommand(query), strings.ToUpper(fr.Command)))
+	}
+	closest := len(query)
+	if !fr.isQueryMatch(query) {
+		var reason string
+		reason, closest = fr.queryDiff(query)
+		reasons = append(reasons, reason)
+	}
+	if !fr.isArgsMatch(args) {
+		reasons = append(reasons, fr.argsDiff(args)...)
+	}
+	return reasons, closest
+}
+
+// queryDiff describes where query differs from pattern
+func (fr *FakeResponse) queryDiff(query string) (string, int) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	pattern := fr.Pattern
+	if fr.Normalized {
+		query = normalizeQuery(query)
+		pattern = normalizeQuery(pattern)
+	}
+	if fr.QueryRegexp != nil {
+		return fmt.Sprintf("query doesn't match regexp %q", fr.queryRegexp().String()), 0
+	}
+
+	if fr.Strict {
+		n := commonPrefix(query, pattern)
+		return fmt.Sprintf("query differs at position %d\n%s", n, diffSnippets(query, pattern, n, n)), n
+	}
+
+	// Looking for the longest beginning of pattern which query contains
+	n, at := 0, 0
+	for k := len(pattern); k > 0; k-- {
+		if i := strings.Index(query, pattern[:k]); i >= 0 {
+			n, at = k, i
+			break
+		}
+	}
+	if n == 0 {
+		return fmt.Sprintf("query doesn't contain pattern\n%s", diffSnippets(query, pattern, 0, 0)), 0
+	}
+	return fmt.Sprintf("query contains only first %d characters of pattern\n%s", n, diffSnippets(query, pattern, at+n, n)), n
+}
+
+// commonPrefix returns length of common beginning of strings
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// diffSnippets shows query and pattern from positions they differ
+func diffSnippets(query, pattern string, queryPos, patternPos int) string {
+	return fmt.Sprintf("  query:   ...%q\n  pattern: ...%q", snippet(query[queryPos:]), snippet(pattern[patternPos:]))
+}
+
+func snippet(s string) string {
+	if len(s) > snippetLen {
+		return s[:snippetLen] + "..."
+	}
+	return s
+}
+
+// argsDiff describes which arguments don't match
+func (fr *FakeResponse) argsDiff(args []driver.NamedValue) []string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	var reasons []string
+	if fr.Args != nil && len(fr.Args) != len(args) {
+		reasons = append(reasons, fmt.Sprintf("expected %d args, got %d: %s", len(fr.Args), len(args), describeNamedValues(args)))
+	} else {
+		for index, expected := range fr.Args {
+			if !isArgMatch(expected, index, args) {
+				reasons = append(reasons, argDiff(expected, index, args))
+			}
+		}
+	}
+	indexes := make([]int, 0, len(fr.ArgsAt))
+	for index := range fr.ArgsAt {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		if index < 0 || index >= len(args) {
+			reasons = append(reasons, fmt.Sprintf("arg #%d expected, got %d args", index, len(args)))
+		} else if expected := fr.ArgsAt[index]; !isArgMatch(expected, index, args) {
+			reasons = append(reasons, argDiff(expected, index, args))
+		}
+	}
+	return reasons
+}
+
+// argDiff describes single not matching argument
+func argDiff(expected interface{}, index int, args []driver.NamedValue) string {
+	if named, ok := expected.(sql.NamedArg); ok {
+		for _, arg := range args {
+			if arg.Name == named.Name {
+				return fmt.Sprintf("arg @%s: expected %s, got %#v", named.Name, describeArg(named.Value), arg.Value)
+			}
+		}
+		return fmt.Sprintf("arg @%s: expected %s, but there is no such arg", named.Name, describeArg(named.Value))
+	}
+	return fmt.Sprintf("arg #%d: expected %s, got %#v", index, describeArg(expected), args[index].Value)
+}
+
+// ErrNoMatch is wrapped by error returned for unmatched query when catcher is bound to the test
+var ErrNoMatch = errors.New("mock_catcher: no mock matched query")
+
+// noMatchError carries report of unmatched query
+type noMatchError struct {
+	report string
+}
+
+func (e *noMatchError) Error() string {
+	return "mock_catcher: " + e.report
+}
+
+// Is makes errors.Is(err, ErrNoMatch) true
+func (e *noMatchError) Is(target error) bool {
+	return target == ErrNoMatch
+}
@@ -3,11 +3,15 @@ package gomocket
 import (
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
-	"log"
-	"reflect"
+	"math/rand"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"testing"
+	"time"
 )
 
 const (
@@ -18,14 +22,67 @@ const (
 // Catcher is global instance of Catcher used for attaching all mocks to connection
 var Catcher *MockCatcher
 
+var (
+	catchersMu sync.Mutex
+	catchers   = make(map[string]*MockCatcher) // Catchers bound to specific DSN
+)
+
 // MockCatcher is global entity to save all mocks aka FakeResponses
 type MockCatcher struct {
-	Mocks                []*FakeResponse // Slice of all mocks
-	Logging              bool            // Do we need to log what we catching?
-	PanicOnEmptyResponse bool            // If not response matches - do we need to panic?
+	Mocks                []*FakeResponse  // Slice of all mocks
+	Logging              bool             // Do we need to log what we catching?
+	Logger               Logger           // Where to log, standard log package if nil
+	PanicOnEmptyResponse bool             // If not response matches - do we need to panic? Ignored when bound to the test
+	Ordered              bool             // Queries must arrive in order mocks were registered
+	TxExpectations       []*TxExpectation // Expected Begin, Commit and Rollback of transactions
+	RejectReadOnlyWrites bool             // Fail INSERT, UPDATE and DELETE inside of read-only transaction
+	Clock                Clock            // Source of time for query delays, real time if nil
+	BackslashEscapes     bool             // Backslash escapes quotes in all string literals, like in MySQL
+	dsn                  string           // DSN catcher is bound to, empty for global Catcher
+	position             int              // Index of expected mock in ordered mode
+	failures             []string         // Violations of expectations found during queries
+	entries              []JournalEntry   // Statements and transaction commands sent to the driver
+	t                    testing.TB       // Test failed by unmatched queries instead of panic
+	unmatched            []string         // Reports of unmatched queries to fail the test with
 	mu                   sync.Mutex
 }
 
+// NewCatcher creates MockCatcher bound to provided DSN. Connections opened with this DSN
+// use mocks of returned catcher instead of global Catcher, so tests using different DSN
+// could be run in parallel
+func NewCatcher(dsn string) *MockCatcher {
+	mc := &MockCatcher{dsn: dsn}
+	mc.Register()
+	catchersMu.Lock()
+	defer catchersMu.Unlock()
+	catchers[dsn] = mc
+	return mc
+}
+
+// catcherFor returns catcher bound to DSN or global Catcher if there is no such
+func catcherFor(dsn string) *MockCatcher {
+	catchersMu.Lock()
+	defer catchersMu.Unlock()
+	if mc, ok := catchers[dsn]; ok {
+		return mc
+	}
+	return Catcher
+}
+
+// DSN returns connection string catcher is bound to
+func (mc *MockCatcher) DSN() string {
+	return mc.dsn
+}
+
+// Unbind detaches catcher from its DSN. Connections with this DSN will use global Catcher again
+func (mc *MockCatcher) Unbind() {
+	catchersMu.Lock()
+	defer catchersMu.Unlock()
+	if catchers[mc.dsn] == mc {
+		delete(catchers, mc.dsn)
+	}
+}
+
 func (mc *MockCatcher) SetLogging(l bool) {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
@@ -45,26 +102,89 @@ func (mc *MockCatcher) Register() {
 
 // Attach several mocks to MockCather. Could be useful to attach mocks from some factories of mocks
 func (mc *MockCatcher) Attach(fr []*FakeResponse) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 	mc.Mocks = append(mc.Mocks, fr...)
 }
 
 // FindResponse finds suitable response by provided
 func (mc *MockCatcher) FindResponse(query string, args []driver.NamedValue) *FakeResponse {
+	return mc.findResponse(&statement{query: query, rendered: renderQuery(query, args, mc.backslashEscapes()), args: args})
+}
+
+// statement holds everything known about executed query
+type statement struct {
+	query    string              // Query as it was prepared
+	rendered string              // Query with placeholders replaced by literals of args
+	args     []driver.NamedValue // Args query executed with
+	tx       *FakeTx             // Current transaction, nil if outside of transaction
+	mock     *FakeResponse       // Mock matched statement
+	start    time.Time           // Time statement started
+	exec     *execution          // Trigger of matched mock
+	spCmd    string              // Savepoint command of statement, empty for other statements
+	spName   string              // Name of savepoint statement works with
+}
+
+// queryFor returns query text mock should be matched against
+func (st *statement) queryFor(fr *FakeResponse) string {
+	if fr.matchesRendered() && st.rendered != "" {
+		return st.rendered
+	}
+	return st.query
+}
+
+// matches checks if mock matches statement. Mock without MatchRenderedQuery matches either
+// query with placeholders or query with literals of args
+func (st *statement) matches(fr *FakeResponse) bool {
+	if fr.IsMatch(st.queryFor(fr), st.args) {
+		return true
+	}
+	return !fr.matchesRendered() && st.rendered != "" && st.rendered != st.query && fr.IsMatch(st.rendered, st.args)
+}
+
+// findResponse finds suitable response for statement and records where matched mock was triggered
+func (mc *MockCatcher) findResponse(st *statement) *FakeResponse {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
-	if mc.Logging {
-		log.Printf("mock_catcher: check query: %s", query)
+	query := st.query
+	mc.logf("mock_catcher: check query: %s", query)
+	if len(st.args) > 0 {
+		mc.logf("mock_catcher: query args: %s", describeNamedValues(st.args))
 	}
 
-	for _, resp := range mc.Mocks {
-		if resp.IsMatch(query, args) {
-			resp.MarkAsTriggered()
-			return resp
+	if mc.Ordered {
+		return mc.findOrdered(st)
+	}
+
+	for i, resp := range mc.Mocks {
+		if st.matches(resp) {
+			mc.logf("mock_catcher: matched mock #%d: %s", i, resp.describe())
+			resp.trigger(st)
+			st.mock = resp
+			return resp.currentStep()
+		}
+	}
+
+	// Like transaction commands, savepoints don't need to be mocked
+	if st.spCmd != "" {
+		mc.logf("mock_catcher: savepoint is not mocked: %s", query)
+		return &FakeResponse{Response: make([]map[string]interface{}, 0), Exceptions: &Exceptions{}}
+	}
+
+	report := mc.unmatchedReport(st)
+	mc.logf("mock_catcher: %s", report)
+
+	if mc.t != nil {
+		mc.unmatched = append(mc.unmatched, report)
+		return &FakeResponse{
+			Response:   make([]map[string]interface{}, 0),
+			Error:      &noMatchError{report},
+			Exceptions: &Exceptions{},
 		}
 	}
 
 	if mc.PanicOnEmptyResponse {
-		panic(fmt.Sprintf("No responses matches query %s ", query))
+		panic(report)
 	}
 
 	// Let's have always dummy version of response
@@ -74,6 +194,125 @@ func (mc *MockCatcher) FindResponse(query string, args []driver.NamedValue) *Fak
 	}
 }
 
+// findOrdered accepts query only if it matches next expected mock. Must be called under lock
+func (mc *MockCatcher) findOrdered(st *statement) *FakeResponse {
+	query := st.query
+	var failure string
+	if mc.position < len(mc.Mocks) {
+		resp := mc.Mocks[mc.position]
+		if st.matches(resp) {
+			mc.logf("mock_catcher: matched mock #%d: %s", mc.position, resp.describe())
+			resp.trigger(st)
+			st.mock = resp
+			if !resp.hasNextStep() {
+				mc.position++
+			}
+			return resp.currentStep()
+		}
+		failure = fmt.Sprintf("query %q arrived out of order, expected mock #%d: %s", query, mc.position, resp.describe())
+	} else {
+		failure = fmt.Sprintf("query %q arrived when all mocks were already triggered", query)
+	}
+	if st.spCmd != "" {
+		mc.logf("mock_catcher: savepoint is not mocked: %s", query)
+		return &FakeResponse{Response: make([]map[string]interface{}, 0), Exceptions: &Exceptions{}}
+	}
+	mc.failures = append(mc.failures, failure)
+	mc.logf("mock_catcher: %s", failure)
+
+	return &FakeResponse{
+		Response:   make([]map[string]interface{}, 0),
+		Error:      errors.New("mock_catcher: " + failure),
+		Exceptions: &Exceptions{},
+	}
+}
+
+// ExpectBegin adds expectation of transaction start
+func (mc *MockCatcher) ExpectBegin() *TxExpectation {
+	return mc.expectTx(txBegin)
+}
+
+// ExpectCommit adds expectation of transaction commit
+func (mc *MockCatcher) ExpectCommit() *TxExpectation {
+	return mc.expectTx(txCommit)
+}
+
+// ExpectRollback adds expectation of transaction rollback
+func (mc *MockCatcher) ExpectRollback() *TxExpectation {
+	return mc.expectTx(txRollback)
+}
+
+func (mc *MockCatcher) expectTx(command string) *TxExpectation {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	te := &TxExpectation{Command: command}
+	mc.TxExpectations = append(mc.TxExpectations, te)
+	return te
+}
+
+// txEvent checks transaction command against expectations and returns error attached to expectation.
+// Commands without expectations are allowed
+func (mc *MockCatcher) txEvent(command string, tx *FakeTx) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.logf("mock_catcher: transaction command: %s", command)
+	for _, te := range mc.TxExpectations {
+		if ok, err := te.trigger(command, tx); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// backslashEscapes reports if backslash escapes quotes in string literals of queries
+func (mc *MockCatcher) backslashEscapes() bool {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.BackslashEscapes
+}
+
+// clock returns Clock used by catcher
+func (mc *MockCatcher) clock() Clock {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.Clock == nil {
+		return realClock{}
+	}
+	return mc.Clock
+}
+
+// WithClock sets clock used to emulate query delays
+// example: clock := NewMockClock(time.Now()); Catcher.Reset().WithClock(clock)
+func (mc *MockCatcher) WithClock(clock Clock) *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Clock = clock
+	return mc
+}
+
+// checkReadOnly returns error if command modifies data inside of read-only transaction and it is not allowed
+func (mc *MockCatcher) checkReadOnly(tx *FakeTx, command string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if !mc.RejectReadOnlyWrites || tx == nil || !tx.ReadOnly() {
+		return nil
+	}
+	switch command {
+	case "INSERT", "UPDATE", "DELETE":
+		return fmt.Errorf("fake_db_driver: %s is not allowed in read-only transaction", command)
+	}
+	return nil
+}
+
+// InOrder turns on ordered mode where queries must arrive in the order mocks were registered.
+// Query out of order fails with error and reported by ExpectationsWereMet
+func (mc *MockCatcher) InOrder() *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Ordered = true
+	return mc
+}
+
 // NewMock creates new FakeResponse and return for chains of attachments
 func (mc *MockCatcher) NewMock() *FakeResponse {
 	mc.mu.Lock()
@@ -88,58 +327,156 @@ func (mc *MockCatcher) Reset() *MockCatcher {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
 	mc.Mocks = make([]*FakeResponse, 0)
+	mc.TxExpectations = nil
+	mc.position = 0
+	mc.failures = nil
+	mc.entries = nil
 	return mc
 }
 
+// FailOnUnmatched binds catcher to the test. Unmatched queries return error wrapping ErrNoMatch
+// instead of empty response or panic and fail the test when it finishes
+func (mc *MockCatcher) FailOnUnmatched(t testing.TB) *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.t = t
+	t.Cleanup(func() {
+		mc.mu.Lock()
+		var unmatched []string
+		if mc.t == t {
+			unmatched = mc.unmatched
+			mc.t = nil
+			mc.unmatched = nil
+		}
+		mc.mu.Unlock()
+		for _, report := range unmatched {
+			t.Errorf("mock_catcher: %s", report)
+		}
+	})
+	return mc
+}
+
+// ExpectationsWereMet returns error listing all mocks and transaction commands which were registered
+// but never triggered, queries which arrived out of order and mocks triggered out of committed transaction
+func (mc *MockCatcher) ExpectationsWereMet() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	problems := make([]string, 0, len(mc.failures))
+	for _, failure := range mc.failures {
+		problems = append(problems, "\t"+failure)
+	}
+	for _, resp := range mc.Mocks {
+		if !resp.isTriggered() {
+			problems = append(problems, "\tnot triggered: "+resp.describe())
+		} else if unused := resp.unusedSteps(); unused > 0 {
+			problems = append(problems, fmt.Sprintf("\t%d responses of sequence not used: %s", unused, resp.describe()))
+		}
+		problems = append(problems, resp.txProblems()...)
+	}
+	for _, te := range mc.TxExpectations {
+		if !te.isTriggered() {
+			problems = append(problems, "\tnot triggered: "+te.Command)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("mock_catcher: expectations were not met:\n%s", strings.Join(problems, "\n"))
+}
+
+// AssertExpectations fails the test if some of registered mocks were not triggered
+func (mc *MockCatcher) AssertExpectations(t testing.TB) {
+	t.Helper()
+	if err := mc.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
 // Exceptions represents	 possible exceptions during query executions
 type Exceptions struct {
 	HookQueryBadConnection func() bool
 	HookExecBadConnection  func() bool
 }
 
-// FakeResponse represents mock of response with holding all required values to return mocked response
-type FakeResponse struct {
-	Pattern      string                            // SQL query pattern to match with
-	Strict       bool                              // Strict SQL query pattern comparison or by strings.Contains()
-	Args         []interface{}                     // List args to be matched with
-	Response     []map[string]interface{}          // Array of rows to be parsed as result
-	Once         bool                              // To trigger only once
-	Triggered    bool                              // If it was triggered at least once
-	Callback     func(string, []driver.NamedValue) // Callback to execute when response triggered
-	RowsAffected int64                             // Defines affected rows count
-	LastInsertID int64                             // ID to be returned for INSERT queries
-	Error        error                             // Any type of error which could happen dur
-	mu           sync.Mutex                        // Used to lock concurrent access to variables
-	*Exceptions
+// ResultSet is one of several result sets returned by a query
+type ResultSet struct {
+	Columns  []string                 // Order of columns in result, sorted keys of first row if empty
+	Response []map[string]interface{} // Array of rows to be parsed as result
 }
 
-// isArgsMatch returns true either when nothing to compare or deep equal check passed
-func (fr *FakeResponse) isArgsMatch(args []driver.NamedValue) bool {
-	fr.mu.Lock()
-	defer fr.mu.Unlock()
-	arguments := make([]interface{}, len(args))
-	if len(args) > 0 {
-		for index, arg := range args {
-			arguments[index] = arg.Value
+// columnNames returns names of columns in result in stable order
+func (rs ResultSet) columnNames() []string {
+	if len(rs.Columns) > 0 {
+		return rs.Columns
+	}
+	columns := make([]string, 0)
+	if len(rs.Response) > 0 {
+		for name := range rs.Response[0] {
+			columns = append(columns, name)
 		}
+		sort.Strings(columns)
 	}
-	return fr.Args == nil || reflect.DeepEqual(fr.Args, arguments)
+	return columns
+}
+
+// FakeResponse represents mock of response with holding all required values to return mocked response
+type FakeResponse struct {
+	Pattern       string                                     // SQL query pattern to match with
+	Strict        bool                                       // Strict SQL query pattern comparison or by strings.Contains()
+	QueryRegexp   *regexp.Regexp                             // Regular expression to match query with, used instead of Pattern
+	Command       string                                     // Type of statement to match with, e.g. SELECT or UPDATE
+	MatchRendered bool                                       // Match query with placeholders replaced by literals of args
+	Normalized    bool                                       // Compare queries after normalization of whitespace, quoting and case
+	Args          []interface{}                              // List args to be matched with
+	ArgsAt        map[int]interface{}                        // Args to be matched by position, other args are not checked
+	Response      []map[string]interface{}                   // Array of rows to be parsed as result
+	Columns       []string                                   // Order of columns in result, sorted keys of first row if empty
+	ResultSets    []ResultSet                                // Several result sets to reply with instead of Response
+	ColumnTypes   []ColumnType                               // Types of columns in Response
+	Once          bool                                       // To trigger only once
+	Triggered     bool                                       // If it was triggered at least once
+	Sequence      []*FakeResponse                            // Responses to be used on subsequent triggers, one per trigger
+	WithinTx      bool                                       // Must be triggered only inside of transaction which is committed
+	Callback      func(string, []driver.NamedValue)          // Callback to execute when response triggered
+	TxCallback    func(string, []driver.NamedValue, *FakeTx) // Callback receiving current transaction, nil if outside of transaction
+	RowsAffected  int64                                      // Defines affected rows count
+	LastInsertID  int64                                      // ID to be returned for INSERT queries
+	Error         error                                      // Any type of error which could happen dur
+	RowError      error                                      // Error returned while reading rows after RowErrorAfter rows
+	RowErrorAfter int                                        // Number of rows successfully read before RowError
+	Delay         time.Duration                              // Time query takes to execute, context could be cancelled meanwhile
+	MaxDelay      time.Duration                              // If greater than Delay, query takes random time between Delay and MaxDelay
+	calls         int                                        // How many times response was triggered
+	foldedRegexp  *regexp.Regexp                             // Case insensitive QueryRegexp used for normalized queries
+	executions    []*execution                               // Transactions response was triggered in
+	mu            sync.Mutex                                 // Used to lock concurrent access to variables
+	*Exceptions
 }
 
 // isQueryMatch returns true if searched query is matched FakeResponse Pattern
 func (fr *FakeResponse) isQueryMatch(query string) bool {
 	fr.mu.Lock()
-        defer fr.mu.Unlock()
-	
-	if fr.Pattern == "" {
+	defer fr.mu.Unlock()
+
+	pattern := fr.Pattern
+	if fr.Normalized {
+		query = normalizeQuery(query)
+		pattern = normalizeQuery(pattern)
+	}
+
+	if fr.QueryRegexp != nil {
+		return fr.queryRegexp().MatchString(query)
+	}
+
+	if pattern == "" {
 		return true
 	}
 
-	if fr.Strict == true && query == fr.Pattern {
+	if fr.Strict == true && query == pattern {
 		return true
 	}
 
-	if fr.Strict == false && strings.Contains(query, fr.Pattern) {
+	if fr.Strict == false && strings.Contains(query, pattern) {
 		return true
 	}
 
@@ -149,12 +486,67 @@ func (fr *FakeResponse) isQueryMatch(query string) bool {
 // IsMatch checks if both query and args matcher's return true and if this is Once mock
 func (fr *FakeResponse) IsMatch(query string, args []driver.NamedValue) bool {
 	fr.mu.Lock()
-	if fr.Once && fr.Triggered {
+	if (fr.Once && fr.Triggered) || fr.isExhausted() {
 		fr.mu.Unlock()
 		return false
 	}
 	fr.mu.Unlock()
-	return fr.isQueryMatch(query) && fr.isArgsMatch(args)
+	return fr.isCommandMatch(query) && fr.isQueryMatch(query) && fr.isArgsMatch(args)
+}
+
+// isCommandMatch returns true if type of query statement is the same as expected
+func (fr *FakeResponse) isCommandMatch(query string) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.Command == "" || strings.EqualFold(fr.Command, classifyCommand(query))
+}
+
+// isTriggered safely checks if response was triggered at least once
+func (fr *FakeResponse) isTriggered() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.Triggered
+}
+
+// describe returns human readable representation of mock used in reports
+func (fr *FakeResponse) describe() string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.describeLocked()
+}
+
+// describeLocked is describe which must be called under lock
+func (fr *FakeResponse) describeLocked() string {
+	desc := fmt.Sprintf("pattern %q", fr.Pattern)
+	if fr.QueryRegexp != nil {
+		desc = fmt.Sprintf("regexp %q", fr.QueryRegexp.String())
+	} else if fr.Pattern == "" {
+		desc = "any query"
+	}
+	if fr.Command != "" {
+		desc = strings.ToUpper(fr.Command) + " " + desc
+	}
+	if fr.Strict {
+		desc += " (strict)"
+	}
+	if fr.Normalized {
+		desc += " (normalized)"
+	}
+	if fr.MatchRendered {
+		desc += " (rendered)"
+	}
+	if fr.Args != nil {
+		desc += " with args " + describeArgs(fr.Args)
+	}
+	indexes := make([]int, 0, len(fr.ArgsAt))
+	for index := range fr.ArgsAt {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		desc += fmt.Sprintf(" with arg #%d %s", index, describeArg(fr.ArgsAt[index]))
+	}
+	return desc
 }
 
 // MarkAsTriggered marks response as executed. For one time catches it will not make this possible to execute anymore
@@ -162,6 +554,135 @@ func (fr *FakeResponse) MarkAsTriggered() {
 	fr.mu.Lock()
 	defer fr.mu.Unlock()
 	fr.Triggered = true
+	fr.calls++
+}
+
+// trigger marks response as triggered by statement and records transaction it was triggered in
+func (fr *FakeResponse) trigger(st *statement) {
+	fr.MarkAsTriggered()
+	e := &execution{tx: st.tx}
+	st.exec = e
+	// Rollback to savepoint is recorded after it is applied, so it doesn't discard itself
+	if st.tx != nil && st.spCmd != spRollback {
+		st.tx.record(e)
+	}
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.executions = append(fr.executions, e)
+}
+
+// txProblems lists triggers of response which happened outside of committed transaction
+func (fr *FakeResponse) txProblems() []string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if !fr.WithinTx {
+		return nil
+	}
+	var problems []string
+	for _, e := range fr.executions {
+		if e.tx == nil {
+			problems = append(problems, fmt.Sprintf("\ttriggered outside of transaction: %s", fr.describeLocked()))
+		} else if status := e.tx.status(e); status != "committed" {
+			problems = append(problems, fmt.Sprintf("\ttriggered in transaction #%d which is %s: %s", e.tx.ID(), status, fr.describeLocked()))
+		}
+	}
+	return problems
+}
+
+// hasNextStep checks if sequence still has responses for next triggers
+func (fr *FakeResponse) hasNextStep() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return len(fr.Sequence) > 0 && fr.calls <= len(fr.Sequence)
+}
+
+// isExhausted checks if all responses of sequence were used. Must be called under lock
+func (fr *FakeResponse) isExhausted() bool {
+	return len(fr.Sequence) > 0 && fr.calls > len(fr.Sequence)
+}
+
+// currentStep returns response of sequence for the last trigger. First trigger uses mock itself
+func (fr *FakeResponse) currentStep() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.calls <= 1 || fr.calls > len(fr.Sequence)+1 {
+		return fr
+	}
+	return fr.inherit(fr.Sequence[fr.calls-2])
+}
+
+// inherit returns copy of mock with fields set in step of sequence. Reply and error are overridden together,
+// so step replying with rows doesn't inherit error of mock and vice versa. Must be called under lock
+func (fr *FakeResponse) inherit(step *FakeResponse) *FakeResponse {
+	resp := &FakeResponse{
+		Pattern:       fr.Pattern,
+		Strict:        fr.Strict,
+		QueryRegexp:   fr.QueryRegexp,
+		Command:       fr.Command,
+		MatchRendered: fr.MatchRendered,
+		Normalized:    fr.Normalized,
+		Args:          fr.Args,
+		ArgsAt:        fr.ArgsAt,
+		Response:      fr.Response,
+		Columns:       fr.Columns,
+		ResultSets:    fr.ResultSets,
+		ColumnTypes:   fr.ColumnTypes,
+		WithinTx:      fr.WithinTx,
+		Callback:      fr.Callback,
+		TxCallback:    fr.TxCallback,
+		RowsAffected:  fr.RowsAffected,
+		LastInsertID:  fr.LastInsertID,
+		Error:         fr.Error,
+		RowError:      fr.RowError,
+		RowErrorAfter: fr.RowErrorAfter,
+		Delay:         fr.Delay,
+		MaxDelay:      fr.MaxDelay,
+		Exceptions:    fr.Exceptions,
+	}
+	if step.Response != nil || step.ResultSets != nil || step.Error != nil {
+		resp.Response, resp.ResultSets, resp.Error = step.Response, step.ResultSets, step.Error
+		if resp.Response == nil {
+			resp.Response = make([]map[string]interface{}, 0)
+		}
+	}
+	if step.Columns != nil {
+		resp.Columns = step.Columns
+	}
+	if step.ColumnTypes != nil {
+		resp.ColumnTypes = step.ColumnTypes
+	}
+	if step.Callback != nil {
+		resp.Callback = step.Callback
+	}
+	if step.TxCallback != nil {
+		resp.TxCallback = step.TxCallback
+	}
+	if step.RowsAffected != 0 {
+		resp.RowsAffected = step.RowsAffected
+	}
+	if step.LastInsertID != 0 {
+		resp.LastInsertID = step.LastInsertID
+	}
+	if step.RowError != nil {
+		resp.RowError, resp.RowErrorAfter = step.RowError, step.RowErrorAfter
+	}
+	if step.Delay != 0 || step.MaxDelay != 0 {
+		resp.Delay, resp.MaxDelay = step.Delay, step.MaxDelay
+	}
+	if step.Exceptions != nil {
+		resp.Exceptions = step.Exceptions
+	}
+	return resp
+}
+
+// unusedSteps returns how many responses of sequence were never used
+func (fr *FakeResponse) unusedSteps() int {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.calls > len(fr.Sequence) {
+		return 0
+	}
+	return len(fr.Sequence) + 1 - max(fr.calls, 1)
 }
 
 // WithQuery adds SQL query pattern to match for
@@ -172,12 +693,71 @@ func (fr *FakeResponse) WithQuery(query string) *FakeResponse {
 	return fr
 }
 
+// WithQueryRegexp adds regular expression to match SQL query with.
+// Expression is compiled once and panics if it is invalid
+func (fr *FakeResponse) WithQueryRegexp(expr string) *FakeResponse {
+	re := regexp.MustCompile(expr)
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Pattern = expr
+	fr.QueryRegexp = re
+	return fr
+}
+
 // WithQuery adds SQL query pattern to match for
 func (fr *FakeResponse) StrictMatch() *FakeResponse {
 	fr.Strict = true
 	return fr
 }
 
+// WithCommand makes mock to match only statements of provided type, e.g. SELECT or UPDATE.
+// For statements with CTE type of the main statement is used
+func (fr *FakeResponse) WithCommand(command string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Command = command
+	return fr
+}
+
+// MatchRenderedQuery makes mock to match only query with placeholders replaced by quoted literals of args,
+// e.g. SELECT * FROM users WHERE name = 'FirstLast' instead of SELECT * FROM users WHERE name = ?.
+// Other mocks match either of them
+func (fr *FakeResponse) MatchRenderedQuery() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.MatchRendered = true
+	return fr
+}
+
+func (fr *FakeResponse) matchesRendered() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.MatchRendered
+}
+
+// NormalizedMatch makes mock to compare queries ignoring differences in whitespace,
+// identifier quoting and keywords case. Works together with StrictMatch and WithQueryRegexp,
+// regular expression is matched case insensitive then
+func (fr *FakeResponse) NormalizedMatch() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Normalized = true
+	return fr
+}
+
+// queryRegexp returns regular expression to match query with. Normalized query has keywords
+// in lower case, so expression is matched case insensitive. Must be called under lock
+func (fr *FakeResponse) queryRegexp() *regexp.Regexp {
+	if !fr.Normalized {
+		return fr.QueryRegexp
+	}
+	expr := "(?i)" + fr.QueryRegexp.String()
+	if fr.foldedRegexp == nil || fr.foldedRegexp.String() != expr {
+		fr.foldedRegexp = regexp.MustCompile(expr)
+	}
+	return fr.foldedRegexp
+}
+
 // WithArgs attaches Args check for prepared statements
 func (fr *FakeResponse) WithArgs(vars ...interface{}) *FakeResponse {
 	if len(vars) > 0 {
@@ -189,6 +769,18 @@ func (fr *FakeResponse) WithArgs(vars ...interface{}) *FakeResponse {
 	return fr
 }
 
+// WithArgAt attaches check of single argument by its position, other arguments are not checked.
+// example: WithArgAt(1, AnyOfType(time.Time{}))
+func (fr *FakeResponse) WithArgAt(index int, v interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.ArgsAt == nil {
+		fr.ArgsAt = make(map[int]interface{})
+	}
+	fr.ArgsAt[index] = v
+	return fr
+}
+
 // WithReply adds to chain and assign some parts of response
 func (fr *FakeResponse) WithReply(response []map[string]interface{}) *FakeResponse {
 	fr.mu.Lock()
@@ -197,6 +789,82 @@ func (fr *FakeResponse) WithReply(response []map[string]interface{}) *FakeRespon
 	return fr
 }
 
+// WithColumns fixes order of columns in reply. Should be called before WithRows
+func (fr *FakeResponse) WithColumns(columns ...string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Columns = columns
+	return fr
+}
+
+// WithRows sets reply as rows of values in order of columns declared by WithColumns
+// example: WithColumns("id", "name").WithRows([]interface{}{1, "FirstLast"})
+func (fr *FakeResponse) WithRows(rows ...[]interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	response := make([]map[string]interface{}, 0, len(rows))
+	for i, values := range rows {
+		if len(values) != len(fr.Columns) {
+			panic(fmt.Sprintf("mock_catcher: row %d has %d values while %d columns declared", i, len(values), len(fr.Columns)))
+		}
+		record := make(map[string]interface{}, len(values))
+		for j, v := range values {
+			record[fr.Columns[j]] = v
+		}
+		response = append(response, record)
+	}
+	fr.Response = response
+	return fr
+}
+
+// resultSets returns all result sets of reply
+func (fr *FakeResponse) resultSets() []ResultSet {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if len(fr.ResultSets) > 0 {
+		return fr.ResultSets
+	}
+	return []ResultSet{{Columns: fr.Columns, Response: fr.Response}}
+}
+
+// WithResultSets sets several result sets to reply with, available via rows.NextResultSet().
+// Replaces reply set by WithReply or WithRows
+func (fr *FakeResponse) WithResultSets(sets ...ResultSet) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ResultSets = sets
+	return fr
+}
+
+// WithColumnTypes declares types of columns in reply to be available via rows.ColumnTypes()
+func (fr *FakeResponse) WithColumnTypes(types ...ColumnType) *FakeResponse {
+	for _, ct := range types {
+		if ct.ScanType != "" {
+			colTypeToReflectType(ct.ScanType) // Fail early on unknown type
+		}
+	}
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ColumnTypes = types
+	return fr
+}
+
+// columnTypesOf returns declared column types in order of provided columns, nil for not declared ones
+func (fr *FakeResponse) columnTypesOf(columns []string) []*ColumnType {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	types := make([]*ColumnType, len(columns))
+	for i, name := range columns {
+		for j := range fr.ColumnTypes {
+			if fr.ColumnTypes[j].Name == name {
+				types[i] = &fr.ColumnTypes[j]
+				break
+			}
+		}
+	}
+	return types
+}
+
 // OneTime sets current mock to be triggered only once
 func (fr *FakeResponse) OneTime() *FakeResponse {
 	fr.Once = true
@@ -225,6 +893,13 @@ func (fr *FakeResponse) WithCallback(f func(string, []driver.NamedValue)) *FakeR
 	return fr
 }
 
+// WithTxCallback adds callback to be executed during matching with current transaction.
+// Transaction is nil for queries outside of transaction
+func (fr *FakeResponse) WithTxCallback(f func(string, []driver.NamedValue, *FakeTx)) *FakeResponse {
+	fr.TxCallback = f
+	return fr
+}
+
 // WithRowsNum specifies how many records to consider as affected
 func (fr *FakeResponse) WithRowsNum(num int64) *FakeResponse {
 	fr.RowsAffected = num
@@ -244,6 +919,75 @@ func (fr *FakeResponse) WithError(err error) *FakeResponse {
 	return fr
 }
 
+// Then adds response to sequence. Mock replies with itself on first trigger and
+// with responses of sequence in order on subsequent ones. Exhausted mock doesn't match anymore.
+// Fields which are not set in step are taken from the mock
+func (fr *FakeResponse) Then(step *FakeResponse) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Sequence = append(fr.Sequence, step)
+	return fr
+}
+
+// ThenReply adds reply with provided rows to sequence
+func (fr *FakeResponse) ThenReply(response []map[string]interface{}) *FakeResponse {
+	if response == nil {
+		response = make([]map[string]interface{}, 0)
+	}
+	return fr.Then(&FakeResponse{Response: response})
+}
+
+// ThenError adds reply with provided error to sequence
+// example: WithReply(rows).ThenError(driver.ErrBadConn).ThenReply(nil)
+func (fr *FakeResponse) ThenError(err error) *FakeResponse {
+	return fr.Then(&FakeResponse{Error: err})
+}
+
+// WithinCommittedTx expects mock to be triggered only inside of transaction which is committed afterwards.
+// Violations are reported by ExpectationsWereMet
+func (fr *FakeResponse) WithinCommittedTx() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.WithinTx = true
+	return fr
+}
+
+// WithDelay makes query to take provided time. Query returns error of context
+// if it is cancelled or its deadline exceeded before delay passes
+func (fr *FakeResponse) WithDelay(d time.Duration) *FakeResponse {
+	fr.Delay = d
+	return fr
+}
+
+// WithRandomDelay makes query to take random time between min and max
+func (fr *FakeResponse) WithRandomDelay(min, max time.Duration) *FakeResponse {
+	fr.Delay = min
+	fr.MaxDelay = max
+	return fr
+}
+
+// delay returns time query should take
+func (fr *FakeResponse) delay(clock Clock) time.Duration {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.MaxDelay > fr.Delay {
+		n := int64(fr.MaxDelay-fr.Delay) + 1
+		if src, ok := clock.(randSource); ok {
+			return fr.Delay + time.Duration(src.Int63n(n))
+		}
+		return fr.Delay + time.Duration(rand.Int63n(n))
+	}
+	return fr.Delay
+}
+
+// WithRowError makes reading of rows fail with provided error after n rows were read.
+// Useful to emulate connection drop in the middle of result
+func (fr *FakeResponse) WithRowError(n int, err error) *FakeResponse {
+	fr.RowErrorAfter = n
+	fr.RowError = err
+	return fr
+}
+
 func init() {
 	Catcher = &MockCatcher{}
 }
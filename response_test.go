@@ -1,9 +1,19 @@
 package gomocket
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 var DB *sql.DB
@@ -102,6 +112,80 @@ func TestResponses(t *testing.T) {
 		}
 	})
 
+	t.Run("Simple SELECT caught by regexp", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQueryRegexp(`^SELECT name, \w+ FROM users WHERE age\s*=`).WithReply(commonReply)
+		result := GetUsers(DB)
+		if len(result) != 1 {
+			t.Fatalf("Returned sets is not equal to 1. Received %d", len(result))
+		}
+		Catcher.Reset().NewMock().WithQueryRegexp(`^SELECT \* FROM users`).WithReply(commonReply)
+		result = GetUsers(DB)
+		if len(result) != 0 {
+			t.Errorf("Returned sets is not equal to 0. Received %d", len(result))
+		}
+	})
+
+	t.Run("Simple SELECT caught by normalized query", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("select  name,age\n\tFROM \"users\"  where AGE = 27").StrictMatch().NormalizedMatch().WithReply(commonReply)
+		result := GetUsers(DB)
+		if len(result) != 1 {
+			t.Fatalf("Returned sets is not equal to 1. Received %d", len(result))
+		}
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users WHERE name = 'firstlast'").NormalizedMatch()
+		if fr := Catcher.FindResponse("SELECT name FROM users WHERE name = 'FirstLast'", nil); fr.isTriggered() {
+			t.Errorf("String literals must be compared case sensitive")
+		}
+		Catcher.Reset().NewMock().WithQueryRegexp(`^SELECT \* FROM users`).NormalizedMatch()
+		if fr := Catcher.FindResponse("SELECT  *\n FROM \"users\"", nil); !fr.isTriggered() {
+			t.Errorf("Normalized query is not matched by regexp")
+		}
+	})
+
+	t.Run("Caught by statement type", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithCommand("UPDATE").WithRowsNum(2)
+		Catcher.NewMock().WithCommand("SELECT").WithReply(commonReply)
+		res, err := DB.Exec("/* user update */ UPDATE users SET name = ?", "FirstLast")
+		if err != nil {
+			t.Fatalf("Update failed [%v]", err)
+		}
+		if affected, _ := res.RowsAffected(); affected != 2 {
+			t.Errorf("Rows affected not returned. Expected: [2] , Got: [%v]", affected)
+		}
+		if result := GetUsers(DB); len(result) != 1 {
+			t.Errorf("Returned sets is not equal to 1. Received %d", len(result))
+		}
+	})
+
+	t.Run("Rendered query", func(t *testing.T) {
+		var queries []string
+		callback := func(query string, _ []driver.NamedValue) { queries = append(queries, query) }
+		Catcher.Reset().NewMock().WithQuery(`name = 'O''Brian' AND age = 27`).MatchRenderedQuery().WithCallback(callback)
+		Catcher.NewMock().WithQuery(`SELECT name FROM users WHERE name = $1`).WithCallback(callback)
+		stmt, err := DB.Prepare(`SELECT name FROM users WHERE name = $1 AND age = $2`)
+		if err != nil {
+			t.Fatalf("Prepare failed [%v]", err)
+		}
+		defer stmt.Close()
+		for _, name := range []string{"O'Brian", "FirstLast"} {
+			rows, err := stmt.Query(name, 27)
+			if err != nil {
+				t.Fatalf("Select failed [%v]", err)
+			}
+			rows.Close()
+		}
+		expected := []string{
+			`SELECT name FROM users WHERE name = 'O''Brian' AND age = 27`,
+			`SELECT name FROM users WHERE name = $1 AND age = $2`,
+		}
+		if !reflect.DeepEqual(queries, expected) {
+			t.Errorf("Unexpected queries in callbacks %q", queries)
+		}
+		Catcher.Reset().NewMock().WithQuery(`WHERE age=27`).WithReply(commonReply)
+		if result := GetUsers(DB); len(result) != 1 {
+			t.Errorf("Query with values of args is not matched. Received %d", len(result))
+		}
+	})
+
 	t.Run("Simple SELECT with direct object", func(t *testing.T) {
 		t.Run("Not a once", func(t *testing.T) {
 			Catcher.Reset()
@@ -141,6 +225,56 @@ func TestResponses(t *testing.T) {
 		})
 	})
 
+	t.Run("Sequence of responses", func(t *testing.T) {
+		errTimeout := errors.New("timeout")
+		Catcher.Reset().NewMock().WithQuery(`SELECT name, age FROM users WHERE`).
+			WithReply(commonReply).
+			ThenError(errTimeout).
+			ThenReply([]map[string]interface{}{})
+		if result := GetUsers(DB); len(result) != 1 {
+			t.Fatalf("Returned sets is not equal to 1. Received %d", len(result))
+		}
+		if err := GetUsersWithError(DB); err != errTimeout {
+			t.Fatalf("Error of second response not returned. Got [%v]", err)
+		}
+		if result := GetUsers(DB); len(result) != 0 {
+			t.Fatalf("Returned sets is not equal to 0. Received %d", len(result))
+		}
+		Catcher.NewMock().WithQuery(`SELECT name, age FROM users WHERE`).WithReply(commonReply)
+		if result := GetUsers(DB); len(result) != 1 {
+			t.Fatalf("Exhausted sequence still matches query")
+		}
+	})
+
+	t.Run("Steps of sequence inherit mock", func(t *testing.T) {
+		calls := 0
+		Catcher.Reset().NewMock().WithQuery(`UPDATE users`).WithRowsNum(5).
+			WithCallback(func(string, []driver.NamedValue) { calls++ }).
+			ThenReply(nil).ThenReply(nil)
+		if err := Catcher.ExpectationsWereMet(); err == nil {
+			t.Errorf("Not triggered sequence is not reported")
+		}
+		for i := 0; i < 2; i++ {
+			res, err := DB.Exec(`UPDATE users SET age = 1`)
+			if err != nil {
+				t.Fatalf("Update failed [%v]", err)
+			}
+			if affected, _ := res.RowsAffected(); affected != 5 {
+				t.Errorf("Rows affected of trigger #%d are not inherited. Got %d", i, affected)
+			}
+		}
+		if err := Catcher.ExpectationsWereMet(); err == nil || !strings.Contains(err.Error(), "1 responses of sequence not used") {
+			t.Errorf("Unused response of sequence is not reported, got: %v", err)
+		}
+		DB.Exec(`UPDATE users SET age = 1`)
+		if calls != 3 {
+			t.Errorf("Callback should be called on each trigger, called %d times", calls)
+		}
+		if err := Catcher.ExpectationsWereMet(); err != nil {
+			t.Errorf("Used sequence is reported: %v", err)
+		}
+	})
+
 	t.Run("Catch by arguments", func(t *testing.T) {
 		fr := Catcher.Reset().NewMock().WithArgs(int64(27)).WithReply(commonReply)
 		t.Log("result", fr)
@@ -154,6 +288,54 @@ func TestResponses(t *testing.T) {
 		}
 	})
 
+	t.Run("Catch by named arguments", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`UPDATE users`).WithArgs(sql.Named("name", "FirstLast"), sql.Named("id", 5)).WithRowsNum(1)
+		res, err := DB.Exec(`UPDATE users SET name = :name WHERE id = :id`, sql.Named("id", 5), sql.Named("name", "FirstLast"))
+		if err != nil {
+			t.Fatalf("Update failed [%v]", err)
+		}
+		if affected, _ := res.RowsAffected(); affected != 1 {
+			t.Errorf("Named arguments are not matched")
+		}
+		res, _ = DB.Exec(`UPDATE users SET name = @name WHERE id = @id`, sql.Named("id", 6), sql.Named("name", "FirstLast"))
+		if affected, _ := res.RowsAffected(); affected != 0 {
+			t.Errorf("Named arguments with different values are matched")
+		}
+	})
+
+	t.Run("Unsupported arguments are rejected", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`UPDATE users`).WithRowsNum(1)
+		for _, arg := range []interface{}{struct{ ID int }{1}, []int{1, 2}, uint64(1 << 63)} {
+			if _, err := DB.Exec(`UPDATE users SET name = ?`, arg); err == nil {
+				t.Errorf("Argument %#v should be rejected like a real driver does", arg)
+			}
+		}
+	})
+
+	t.Run("Catch by argument matchers", func(t *testing.T) {
+		now := time.Now()
+		Catcher.Reset().NewMock().WithQuery(`INSERT INTO users`).
+			WithArgs(ArgRegexp(`^First`), AnyArg(), AnyOfType(0), ArgTimeNear(now, time.Second)).WithID(1)
+		Catcher.NewMock().WithQuery(`INSERT INTO users`).WithArgAt(0, ArgPredicate(func(v driver.Value) bool {
+			return v == "Predicate"
+		})).WithID(2)
+		insert := func(name string) int64 {
+			res, err := DB.Exec(`INSERT INTO users (name, uuid, age, created_at) VALUES (?, ?, ?, ?)`,
+				name, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", 27, now.Add(time.Millisecond))
+			if err != nil {
+				t.Fatalf("Insert failed [%v]", err)
+			}
+			id, _ := res.LastInsertId()
+			return id
+		}
+		if id := insert("FirstLast"); id != 1 {
+			t.Errorf("Arguments are not matched by matchers. Got ID %d", id)
+		}
+		if id := insert("Predicate"); id != 2 {
+			t.Errorf("Argument is not matched by position. Got ID %d", id)
+		}
+	})
+
 	t.Run("Exceptions and Errors", func(t *testing.T) {
 		t.Run("Fire Query error", func(t *testing.T) {
 			Catcher.Reset().NewMock().WithArgs(int64(27)).WithReply(commonReply).WithQueryException()
@@ -178,6 +360,57 @@ func TestResponses(t *testing.T) {
 		})
 	})
 
+	t.Run("Fire error while reading rows", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`SELECT name, age FROM users WHERE`).
+			WithReply([]map[string]interface{}{{"name": "FirstLast"}, {"name": "SecondLast"}, {"name": "ThirdLast"}}).
+			WithRowError(2, driver.ErrBadConn)
+		rows, err := DB.Query("SELECT name, age FROM users WHERE age=?", 27)
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		defer rows.Close()
+		var count int
+		for rows.Next() {
+			count++
+		}
+		if count != 2 {
+			t.Errorf("Read rows is not equal to 2. Received %d", count)
+		}
+		if rows.Err() != driver.ErrBadConn {
+			t.Errorf("Row error not triggered. Got [%v]", rows.Err())
+		}
+	})
+
+	t.Run("Context cancellation", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`SELECT name, age FROM users WHERE`).WithReply(commonReply).WithDelay(time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := DB.QueryContext(ctx, "SELECT name, age FROM users WHERE age=?", 27); err != context.DeadlineExceeded {
+			t.Fatalf("Deadline is not honored. Got [%v]", err)
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		if _, err := DB.ExecContext(ctx, "UPDATE users SET age = ?", 27); err != context.Canceled {
+			t.Fatalf("Cancellation is not honored. Got [%v]", err)
+		}
+	})
+
+	t.Run("Exec of any statement", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`WITH archived AS`).WithRowsNum(3)
+		for _, query := range []string{`CREATE TABLE foo (id INT)`, `TRUNCATE foo`, `SET search_path TO public`} {
+			if _, err := DB.Exec(query); err != nil {
+				t.Fatalf("Exec of %q failed [%v]", query, err)
+			}
+		}
+		res, err := DB.Exec(`WITH archived AS (SELECT id FROM users) UPDATE orders SET archived = true`)
+		if err != nil {
+			t.Fatalf("Exec failed [%v]", err)
+		}
+		if affected, _ := res.RowsAffected(); affected != 3 {
+			t.Errorf("Rows affected not returned. Expected: [3] , Got: [%v]", affected)
+		}
+	})
+
 	t.Run("Last insert id", func(t *testing.T) {
 		var mockedID int64
 		mockedID = 64
@@ -188,6 +421,104 @@ func TestResponses(t *testing.T) {
 		}
 	})
 
+	t.Run("Expectations were met", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`SELECT name, age FROM users WHERE`).WithReply(commonReply)
+		Catcher.NewMock().WithQuery(`UPDATE users SET`).WithArgs("FirstLast")
+		GetUsers(DB)
+		err := Catcher.ExpectationsWereMet()
+		if err == nil {
+			t.Fatal("Not triggered UPDATE mock is not reported")
+		}
+		if !strings.Contains(err.Error(), `"UPDATE users SET"`) || strings.Contains(err.Error(), "SELECT") {
+			t.Fatalf("Unexpected report of not triggered mocks: %v", err)
+		}
+		if _, err := DB.Exec(`UPDATE users SET name = ?`, "FirstLast"); err != nil {
+			t.Fatalf("Update failed [%v]", err)
+		}
+		Catcher.AssertExpectations(t)
+	})
+
+	t.Run("Ordered columns", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`SELECT name, age FROM users WHERE`).
+			WithColumns("name", "age").
+			WithRows([]interface{}{"FirstLast", "30"}, []interface{}{"SecondLast", "40"})
+		for i := 0; i < 10; i++ {
+			rows, err := DB.Query("SELECT name, age FROM users WHERE age=?", 27)
+			if err != nil {
+				t.Fatalf("Query failed [%v]", err)
+			}
+			columns, _ := rows.Columns()
+			if !reflect.DeepEqual(columns, []string{"name", "age"}) {
+				t.Fatalf("Unexpected order of columns %v", columns)
+			}
+			var name, age string
+			rows.Next()
+			if err := rows.Scan(&name, &age); err != nil || name != "FirstLast" || age != "30" {
+				t.Fatalf("Unexpected row [%v, %v] [%v]", name, age, err)
+			}
+			rows.Close()
+		}
+	})
+
+	t.Run("Multiple result sets", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`CALL get_user_with_orders`).WithResultSets(
+			ResultSet{Response: []map[string]interface{}{{"name": "FirstLast"}}},
+			ResultSet{Columns: []string{"id", "total"}, Response: []map[string]interface{}{{"id": 1, "total": 10}, {"id": 2, "total": 20}}},
+		)
+		rows, err := DB.Query(`CALL get_user_with_orders(?)`, 27)
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		defer rows.Close()
+		var name string
+		if !rows.Next() || rows.Scan(&name) != nil || name != "FirstLast" {
+			t.Fatalf("First result set is not returned")
+		}
+		if !rows.NextResultSet() {
+			t.Fatalf("Second result set is not returned [%v]", rows.Err())
+		}
+		if columns, _ := rows.Columns(); !reflect.DeepEqual(columns, []string{"id", "total"}) {
+			t.Fatalf("Unexpected columns of second result set %v", columns)
+		}
+		var count int
+		for rows.Next() {
+			count++
+		}
+		if count != 2 {
+			t.Errorf("Returned sets is not equal to 2. Received %d", count)
+		}
+		if rows.NextResultSet() {
+			t.Errorf("Unexpected third result set")
+		}
+	})
+
+	t.Run("Column types", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery(`SELECT price FROM goods`).
+			WithReply([]map[string]interface{}{{"price": 10.5}}).
+			WithColumnTypes(ColumnType{Name: "price", ScanType: "nullfloat64", DatabaseType: "DECIMAL", Nullable: true, Precision: 10, Scale: 2})
+		rows, err := DB.Query(`SELECT price FROM goods`)
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		defer rows.Close()
+		types, err := rows.ColumnTypes()
+		if err != nil || len(types) != 1 {
+			t.Fatalf("Column types not returned [%v]", err)
+		}
+		if types[0].DatabaseTypeName() != "DECIMAL" || types[0].ScanType() != reflect.TypeOf(sql.NullFloat64{}) {
+			t.Errorf("Unexpected column type %v %v", types[0].DatabaseTypeName(), types[0].ScanType())
+		}
+		if nullable, ok := types[0].Nullable(); !nullable || !ok {
+			t.Errorf("Column is not nullable")
+		}
+		if precision, scale, ok := types[0].DecimalSize(); precision != 10 || scale != 2 || !ok {
+			t.Errorf("Unexpected decimal size %d, %d", precision, scale)
+		}
+		if _, ok := types[0].Length(); ok {
+			t.Errorf("Length is not declared")
+		}
+	})
+
 	t.Run(`Recognise both ? and $1 Postgres placeholders for raw query`, func(t *testing.T) {
 		t.Run("Question mark", func(t *testing.T) {
 			testFunc := func(db *sql.DB) string {
@@ -208,5 +539,408 @@ func TestResponses(t *testing.T) {
 			}
 
 		})
+
+		t.Run("Repeated placeholder", func(t *testing.T) {
+			Catcher.Reset().NewMock().WithQuery("UPDATE foo")
+			if _, err := DB.Exec(`UPDATE foo SET a = $1 WHERE b = $1 AND c = '$2'`, "value"); err != nil {
+				t.Fatalf("Arguments count mismatch [%v]", err)
+			}
+			if _, err := DB.Exec(`UPDATE foo SET a = ? WHERE b = ?`, "value"); err == nil {
+				t.Fatal("Missing argument is not reported")
+			}
+		})
+	})
+}
+
+func TestIsolatedCatchers(t *testing.T) {
+	for _, name := range []string{"first", "second"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			catcher := NewCatcher("isolated_" + name)
+			defer catcher.Unbind()
+			db, _ := sql.Open(DriverName, catcher.DSN())
+			defer db.Close()
+
+			reply := []map[string]interface{}{{"name": name, "age": "30"}}
+			for i := 0; i < 10; i++ {
+				catcher.Reset().NewMock().WithQuery(`SELECT name, age FROM users WHERE`).WithReply(reply)
+				result := GetUsers(db)
+				if len(result) != 1 {
+					t.Fatalf("Returned sets is not equal to 1. Received %d", len(result))
+				}
+				if result[0]["name"] != name {
+					t.Fatalf("Reply of other catcher received. Expected: [%v] , Got: [%v]", name, result[0]["name"])
+				}
+			}
+		})
+	}
+}
+
+func TestOrderedCatcher(t *testing.T) {
+	catcher := NewCatcher("ordered")
+	defer catcher.Unbind()
+	db, _ := sql.Open(DriverName, catcher.DSN())
+	defer db.Close()
+
+	t.Run("Queries in order", func(t *testing.T) {
+		catcher.Reset().InOrder()
+		catcher.NewMock().WithQuery(`INSERT INTO users`)
+		catcher.NewMock().WithQuery(`UPDATE users`)
+		if _, err := db.Exec(`INSERT INTO users (name) VALUES (?)`, "FirstLast"); err != nil {
+			t.Fatalf("Insert failed [%v]", err)
+		}
+		if _, err := db.Exec(`UPDATE users SET age = ?`, 30); err != nil {
+			t.Fatalf("Update failed [%v]", err)
+		}
+		catcher.AssertExpectations(t)
+	})
+
+	t.Run("Queries out of order", func(t *testing.T) {
+		catcher.Reset().InOrder()
+		catcher.NewMock().WithQuery(`INSERT INTO users`)
+		catcher.NewMock().WithQuery(`UPDATE users`)
+		if _, err := db.Exec(`UPDATE users SET age = ?`, 30); err == nil {
+			t.Fatal("Out of order query is not failed")
+		}
+		err := catcher.ExpectationsWereMet()
+		if err == nil || !strings.Contains(err.Error(), "out of order") {
+			t.Fatalf("Out of order query is not reported [%v]", err)
+		}
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	catcher := NewCatcher("transactions")
+	defer catcher.Unbind()
+	db, _ := sql.Open(DriverName, catcher.DSN())
+	defer db.Close()
+
+	t.Run("Committed transaction", func(t *testing.T) {
+		catcher.Reset().ExpectBegin()
+		catcher.NewMock().WithQuery(`UPDATE users`).WithinCommittedTx()
+		catcher.ExpectCommit()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Begin failed [%v]", err)
+		}
+		if _, err := tx.Exec(`UPDATE users SET age = ?`, 30); err != nil {
+			t.Fatalf("Update failed [%v]", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed [%v]", err)
+		}
+		catcher.AssertExpectations(t)
+	})
+
+	t.Run("Rolled back and outside of transaction", func(t *testing.T) {
+		catcher.Reset().NewMock().WithQuery(`UPDATE users`).WithinCommittedTx()
+		catcher.ExpectRollback()
+		tx, _ := db.Begin()
+		tx.Exec(`UPDATE users SET age = ?`, 30)
+		tx.Rollback()
+		db.Exec(`UPDATE users SET age = ?`, 30)
+		err := catcher.ExpectationsWereMet()
+		if err == nil || !strings.Contains(err.Error(), "rolled back") || !strings.Contains(err.Error(), "outside of transaction") {
+			t.Fatalf("Triggers out of committed transaction are not reported [%v]", err)
+		}
+	})
+
+	t.Run("Read-only transaction", func(t *testing.T) {
+		opts := sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+		var isolation sql.IsolationLevel
+		catcher.Reset().ExpectBegin().WithTxOptions(opts)
+		catcher.NewMock().WithQuery(`SELECT name`).WithTxCallback(func(_ string, _ []driver.NamedValue, tx *FakeTx) {
+			isolation = tx.Isolation()
+		})
+		catcher.RejectReadOnlyWrites = true
+		defer func() { catcher.RejectReadOnlyWrites = false }()
+
+		tx, err := db.BeginTx(context.Background(), &opts)
+		if err != nil {
+			t.Fatalf("Begin failed [%v]", err)
+		}
+		defer tx.Rollback()
+		rows, err := tx.Query(`SELECT name FROM users`)
+		if err != nil {
+			t.Fatalf("Select failed [%v]", err)
+		}
+		rows.Close()
+		if isolation != sql.LevelSerializable {
+			t.Errorf("Isolation level is not passed to callback. Got %v", isolation)
+		}
+		if _, err := tx.Exec(`INSERT INTO users (name) VALUES (?)`, "FirstLast"); err == nil {
+			t.Errorf("Write in read-only transaction is not rejected")
+		}
+		catcher.AssertExpectations(t)
+	})
+
+	t.Run("Savepoints", func(t *testing.T) {
+		catcher.Reset().NewMock().WithQuery(`INSERT INTO orders`).WithinCommittedTx()
+		catcher.NewMock().WithQuery(`INSERT INTO items`).WithinCommittedTx()
+		tx, _ := db.Begin()
+		tx.Exec(`INSERT INTO orders (id) VALUES (?)`, 1)
+		if _, err := tx.Exec(`SAVEPOINT sp1`); err != nil {
+			t.Fatalf("Savepoint failed [%v]", err)
+		}
+		tx.Exec(`INSERT INTO items (id) VALUES (?)`, 1)
+		if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT sp1`); err != nil {
+			t.Fatalf("Rollback to savepoint failed [%v]", err)
+		}
+		if _, err := tx.Exec(`RELEASE SAVEPOINT sp2`); err == nil {
+			t.Errorf("Release of unknown savepoint is not failed")
+		}
+		tx.Commit()
+		err := catcher.ExpectationsWereMet()
+		if err == nil || !strings.Contains(err.Error(), "rolled back to savepoint sp1") || strings.Contains(err.Error(), "orders") {
+			t.Fatalf("Partial rollback is not reported [%v]", err)
+		}
+
+		catcher.Reset().NewMock().WithQuery(`ROLLBACK TO SAVEPOINT`).WithError(driver.ErrBadConn)
+		tx, _ = db.Begin()
+		defer tx.Rollback()
+		tx.Exec(`SAVEPOINT sp1`)
+		if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT sp1`); err == nil {
+			t.Errorf("Rollback to savepoint error not triggered")
+		}
+	})
+
+	t.Run("Savepoints are not required to be mocked", func(t *testing.T) {
+		rt := &recordingT{TB: t}
+		catcher.Reset().InOrder().FailOnUnmatched(rt)
+		defer func() { catcher.Ordered = false }()
+		catcher.NewMock().WithQuery(`INSERT INTO orders`)
+		catcher.NewMock().WithQuery(`ROLLBACK TO SAVEPOINT`).WithinCommittedTx()
+		tx, _ := db.Begin()
+		for _, query := range []string{`SAVEPOINT sp1`, `INSERT INTO orders (id) VALUES (1)`, `ROLLBACK TO SAVEPOINT sp1`, `RELEASE SAVEPOINT sp1`} {
+			if _, err := tx.Exec(query); err != nil {
+				t.Errorf("Query %q failed [%v]", query, err)
+			}
+		}
+		tx.Commit()
+		for _, cleanup := range rt.cleanups {
+			cleanup()
+		}
+		if len(rt.errors) != 0 {
+			t.Errorf("Savepoints are reported as unmatched: %v", rt.errors)
+		}
+		if err := catcher.ExpectationsWereMet(); err != nil {
+			t.Errorf("Rollback to savepoint is discarded by itself or savepoints are out of order: %v", err)
+		}
+	})
+
+	t.Run("Journal", func(t *testing.T) {
+		catcher.Reset()
+		mock := catcher.NewMock().WithQuery(`UPDATE users`)
+		tx, _ := db.Begin()
+		tx.Exec(`UPDATE users SET age = ?`, 30)
+		tx.Commit()
+		if rows, err := db.Query(`SELECT name FROM users`); err == nil {
+			rows.Close()
+		}
+
+		var commands []string
+		for _, entry := range catcher.Journal() {
+			commands = append(commands, entry.Command)
+		}
+		expected := []string{JournalBegin, JournalPrepare, JournalExec, JournalCommit, JournalPrepare, JournalQuery}
+		if !reflect.DeepEqual(commands, expected) {
+			t.Fatalf("Unexpected journal %v", commands)
+		}
+		entries := catcher.JournalFor(mock)
+		if len(entries) != 1 || entries[0].TxID == 0 || entries[0].ConnID == 0 || entries[0].Args[0].Value != int64(30) {
+			t.Fatalf("Unexpected journal entries of mock %+v", entries)
+		}
+		if queries := catcher.JournalOf(JournalQuery); len(queries) != 1 || queries[0].TxID != 0 || queries[0].Mock != nil {
+			t.Fatalf("Unexpected journal entries of queries %+v", queries)
+		}
+	})
+
+	t.Run("Commit error", func(t *testing.T) {
+		catcher.Reset().ExpectCommit().WithError(driver.ErrBadConn)
+		tx, _ := db.Begin()
+		if err := tx.Commit(); err != driver.ErrBadConn {
+			t.Fatalf("Commit error not triggered. Got [%v]", err)
+		}
+		catcher.ExpectBegin().WithError(sql.ErrConnDone)
+		if _, err := db.Begin(); err != sql.ErrConnDone {
+			t.Fatalf("Begin error not triggered. Got [%v]", err)
+		}
+	})
+}
+
+func TestMockClock(t *testing.T) {
+	catcher := NewCatcher("clock")
+	defer catcher.Unbind()
+	db, _ := sql.Open(DriverName, catcher.DSN())
+	defer db.Close()
+	clock := NewMockClock(time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC))
+	catcher.Reset().WithClock(clock).NewMock().WithQuery(`UPDATE users`).WithRandomDelay(time.Second, 2*time.Second)
+
+	done := make(chan error)
+	go func() {
+		_, err := db.Exec(`UPDATE users SET age = ?`, 30)
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("Query finished before clock was advanced")
+	default:
+	}
+	clock.Advance(2 * time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("Update failed [%v]", err)
+	}
+	if clock.Now() != time.Date(2018, 1, 1, 12, 0, 2, 0, time.UTC) {
+		t.Errorf("Unexpected time of clock %v", clock.Now())
+	}
+
+	mock := &FakeResponse{Delay: time.Second, MaxDelay: 2 * time.Second}
+	first := mock.delay(NewMockClock(time.Now()).WithRand(rand.New(rand.NewSource(1))))
+	second := mock.delay(NewMockClock(time.Now()).WithRand(rand.New(rand.NewSource(1))))
+	if first != second || first < time.Second || first > 2*time.Second {
+		t.Errorf("Random delay is not reproducible with the same source: %v and %v", first, second)
+	}
+}
+
+func TestClassifyCommand(t *testing.T) {
+	queries := map[string]string{
+		"  \n\tselect * FROM users":                                            "SELECT",
+		"-- comment with UPDATE\nINSERT INTO users VALUES (1)":                 "INSERT",
+		"/* DELETE /* nested */ */ UPDATE users SET a = 1":                     "UPDATE",
+		"(SELECT 1) UNION (SELECT 2)":                                          "SELECT",
+		"WITH a AS (SELECT 1), b (id) AS (SELECT ')') DELETE FROM users":       "DELETE",
+		"WITH RECURSIVE t AS NOT MATERIALIZED (SELECT 1) UPDATE users SET a=1": "UPDATE",
+		"":                      "",
+		"SAVEPOINT sp1":         "SAVEPOINT",
+		"$$ not a statement $$": "",
+	}
+	for query, command := range queries {
+		if got := classifyCommand(query); got != command {
+			t.Errorf("Wrong command of %q. Expected: [%v] , Got: [%v]", query, command, got)
+		}
+	}
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	queries := map[string]int{
+		"SELECT * FROM users WHERE name = ? AND note = '?' -- ?":        1,
+		"SELECT * FROM users WHERE a = $1 AND b = $2 OR c = $1":         2,
+		"SELECT data ?| array['a'] FROM t WHERE id = $1::int":           1,
+		"SELECT * FROM t WHERE a = ?2 AND b = ?1":                       2,
+		"SELECT * FROM users WHERE id = :id AND name = :name":           -1,
+		"SELECT * FROM users WHERE id = @p1 AND @@ROWCOUNT > 0":         -1,
+		"SET @row := 0; SELECT @row := @row + 1 FROM users WHERE a = ?": 1,
+		"SELECT '$1', \"?\" FROM users /* :id */":                       0,
+		"SELECT * FROM t WHERE a = E'it\\'s' AND b = ?":                 1,
+		"SELECT * FROM t WHERE a = e'\\\\' AND b = ? AND c = 'it''s?'":  1,
+		"SELECT * FROM t WHERE a LIKE $1 ESCAPE '\\' AND b = $2":        2,
+		"SELECT * FROM t WHERE a LIKE ? ESCAPE '\\' AND b = ?":          2,
+	}
+	for query, count := range queries {
+		if got := countPlaceholders(query, false); got != count {
+			t.Errorf("Wrong placeholders count of %q. Expected: [%v] , Got: [%v]", query, count, got)
+		}
+	}
+	mysql := "SELECT * FROM t WHERE a = 'it\\'s' AND b = ?"
+	if got := countPlaceholders(mysql, true); got != 1 {
+		t.Errorf("Wrong placeholders count of %q with backslash escapes. Expected: [1] , Got: [%v]", mysql, got)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	catcher := NewCatcher("logger")
+	defer catcher.Unbind()
+	db, _ := sql.Open(DriverName, catcher.DSN())
+	defer db.Close()
+
+	var buf bytes.Buffer
+	catcher.Reset().WithLogger(SlogLogger(slog.New(slog.NewTextHandler(&buf, nil)), slog.LevelInfo))
+	catcher.NewMock().WithQuery(`UPDATE users`).WithArgs("SecondLast")
+	db.Exec(`UPDATE users SET name = ?`, "FirstLast")
+	for _, message := range []string{"check query: UPDATE users SET name = ?", `query args: [\"FirstLast\"]`, "no mock matched", "arg #0: expected"} {
+		if !strings.Contains(buf.String(), message) {
+			t.Errorf("Message %q is not logged:\n%s", message, buf.String())
+		}
+	}
+
+	catcher.Reset().WithLogger(TestLogger(t))
+	catcher.NewMock().WithQuery(`UPDATE users`)
+	db.Exec(`UPDATE users SET name = ?`, "FirstLast")
+}
+
+func TestUnmatchedReport(t *testing.T) {
+	catcher := NewCatcher("report")
+	defer catcher.Unbind()
+	catcher.NewMock().WithQuery(`SELECT * FROM orders`)
+	catcher.NewMock().WithQuery(`SELECT * FROM users WHERE name = ?`).WithArgs("Bob").StrictMatch()
+	catcher.NewMock().WithQuery(`SELECT * FROM users`).OneTime().MarkAsTriggered()
+	catcher.NewMock().WithCommand("delete")
+
+	report := catcher.unmatchedReport(&statement{
+		query: `SELECT * FROM users WHERE name = $1`,
+		args:  []driver.NamedValue{{Ordinal: 1, Value: "Alice"}},
 	})
+	expected := []string{
+		`no mock matched query: SELECT * FROM users WHERE name = $1`,
+		`mock #2 pattern "SELECT * FROM users":` + "\n\t\talready triggered once",
+		`query differs at position 33`,
+		`arg #0: expected "Bob", got "Alice"`,
+		`query contains only first 14 characters of pattern`,
+		`statement type is "SELECT", expected "DELETE"`,
+	}
+	for _, message := range expected {
+		if !strings.Contains(report, message) {
+			t.Errorf("Report doesn't contain %q:\n%s", message, report)
+		}
+	}
+	if strings.Index(report, "mock #2") > strings.Index(report, "mock #0") {
+		t.Errorf("Closest mock should be listed first:\n%s", report)
+	}
+}
+
+// recordingT collects failures and cleanups instead of failing the test
+type recordingT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func TestFailOnUnmatched(t *testing.T) {
+	catcher := NewCatcher("fail_on_unmatched")
+	defer catcher.Unbind()
+	db, _ := sql.Open(DriverName, catcher.DSN())
+	defer db.Close()
+
+	rt := &recordingT{TB: t}
+	catcher.PanicOnEmptyResponse = true
+	catcher.FailOnUnmatched(rt).NewMock().WithQuery(`SELECT * FROM orders`)
+
+	if _, err := db.Exec(`SELECT * FROM orders`); err != nil {
+		t.Errorf("Matched query should not fail: %v", err)
+	}
+	_, err := db.Exec(`SELECT * FROM users`)
+	if !errors.Is(err, ErrNoMatch) || !strings.Contains(err.Error(), `mock #0 pattern "SELECT * FROM orders"`) {
+		t.Errorf("Unmatched query should return report wrapping ErrNoMatch, got: %v", err)
+	}
+	if len(rt.errors) != 0 || len(rt.cleanups) != 1 {
+		t.Fatalf("Test should fail only when it finishes, errors: %v, cleanups: %d", rt.errors, len(rt.cleanups))
+	}
+
+	rt.cleanups[0]()
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "no mock matched query: SELECT * FROM users") {
+		t.Errorf("Unmatched query should fail the test, errors: %v", rt.errors)
+	}
+	if catcher.t != nil {
+		t.Error("Catcher should be unbound from finished test")
+	}
 }
@@ -11,8 +11,8 @@ import (
 
 // RowsCursor is implementation of Rows sql interface
 type RowsCursor struct {
-	cols    []string
-	colType [][]string
+	cols    [][]string
+	colType [][]*ColumnType
 	posSet  int
 	posRow  int
 	rows    [][]*row
@@ -25,6 +25,17 @@ type RowsCursor struct {
 	bytesClone map[*byte][]byte
 }
 
+// ColumnType describes column of mocked result set for rows.ColumnTypes()
+type ColumnType struct {
+	Name         string // Name of column in reply
+	ScanType     string // One of: bool, nullbool, int32, string, nullstring, int64, nullint64, float64, nullfloat64, datetime, bytes
+	DatabaseType string // Database type name, e.g. VARCHAR or INT
+	Nullable     bool   // If column may contain NULL
+	Length       int64  // Length of variable length types, 0 if not applicable
+	Precision    int64  // Precision of decimal types, 0 if not applicable
+	Scale        int64  // Scale of decimal types
+}
+
 type row struct {
 	cols []interface{} // must be same size as its table colname + coltype
 }
@@ -42,13 +53,57 @@ func (rc *RowsCursor) Close() error {
 
 // Columns returns the names of the columns.
 func (rc *RowsCursor) Columns() []string {
-	return rc.cols
+	return rc.cols[rc.posSet]
+}
+
+// columnType returns declared type of column in current result set or nil
+func (rc *RowsCursor) columnType(index int) *ColumnType {
+	if rc.posSet >= len(rc.colType) || index >= len(rc.colType[rc.posSet]) {
+		return nil
+	}
+	return rc.colType[rc.posSet][index]
 }
 
 // ColumnTypeScanType may be implemented by Rows. It should return
 // the value type that can be used to scan types into.
 func (rc *RowsCursor) ColumnTypeScanType(index int) reflect.Type {
-	return colTypeToReflectType(rc.colType[rc.posSet][index])
+	ct := rc.columnType(index)
+	if ct == nil || ct.ScanType == "" {
+		return reflect.TypeOf(new(interface{})).Elem()
+	}
+	return colTypeToReflectType(ct.ScanType)
+}
+
+// ColumnTypeDatabaseTypeName returns the database system type name of the column
+func (rc *RowsCursor) ColumnTypeDatabaseTypeName(index int) string {
+	if ct := rc.columnType(index); ct != nil {
+		return ct.DatabaseType
+	}
+	return ""
+}
+
+// ColumnTypeNullable reports if column may be NULL. ok is false when column type was not declared
+func (rc *RowsCursor) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if ct := rc.columnType(index); ct != nil {
+		return ct.Nullable, true
+	}
+	return false, false
+}
+
+// ColumnTypeLength returns length of variable length column types
+func (rc *RowsCursor) ColumnTypeLength(index int) (length int64, ok bool) {
+	if ct := rc.columnType(index); ct != nil && ct.Length > 0 {
+		return ct.Length, true
+	}
+	return 0, false
+}
+
+// ColumnTypePrecisionScale returns precision and scale of decimal column types
+func (rc *RowsCursor) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if ct := rc.columnType(index); ct != nil && ct.Precision > 0 {
+		return ct.Precision, ct.Scale, true
+	}
+	return 0, 0, false
 }
 
 // Next is called to populate the next row of data into
@@ -121,6 +176,8 @@ func colTypeToReflectType(typ string) reflect.Type {
 		return reflect.TypeOf(sql.NullFloat64{})
 	case "datetime":
 		return reflect.TypeOf(time.Time{})
+	case "bytes":
+		return reflect.TypeOf([]byte(nil))
 	}
 	panic("invalid fakedb column type of " + typ)
 }
@@ -6,14 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"strings"
+	"time"
 )
 
 // FakeStmt  is implementation of Stmt sql interfcae
 type FakeStmt struct {
 	connection   *FakeConn
 	q            string    // just for debugging SQL query generated by sql package
-	command      string    // String name of the command SELECT etc, taken as first word in the query
+	command      string    // String name of the command SELECT etc, type of main statement found by classifyCommand
 	next         *FakeStmt // used for returning multiple results.
 	closed       bool      // If connection closed already
 	colName      []string  // Names of columns in response
@@ -27,6 +27,11 @@ func (s *FakeStmt) ColumnConverter(idx int) driver.ValueConverter {
 	return driver.DefaultParameterConverter
 }
 
+// CheckNamedValue checks arguments the same way as connection does
+func (s *FakeStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	return s.connection.CheckNamedValue(nv)
+}
+
 // Close closes the connection
 func (s *FakeStmt) Close() error {
 	// No connection added
@@ -45,8 +50,37 @@ func (s *FakeStmt) Close() error {
 	return nil
 }
 
+// statement describes execution of prepared statement with provided args.
+// Statement itself is not changed, so it could be executed again with other args
+func (s *FakeStmt) statement(args []driver.NamedValue) *statement {
+	st := &statement{
+		query:    s.q,
+		rendered: renderQuery(s.q, args, s.connection.catcher().backslashEscapes()),
+		args:     args,
+		tx:       s.connection.currTx,
+		start:    s.connection.catcher().clock().Now(),
+	}
+	st.spCmd, st.spName, _ = parseSavepoint(s.q)
+	return st
+}
+
 var errClosed = errors.New("fake_db_driver: statement has been closed")
 
+// waitFor emulates execution of query during provided time. Returns error of context if it is done earlier
+func waitFor(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
 // Exec executes a query that doesn't return rows, such
 // as an INSERT or UPDATE.
 //
@@ -57,12 +91,25 @@ func (s *FakeStmt) Exec(args []driver.Value) (driver.Result, error) {
 
 // ExecContext executes a query that doesn't return rows, such
 // as an INSERT or UPDATE.
-func (s *FakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+func (s *FakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (_ driver.Result, err error) {
+	st := s.statement(args)
+	defer func() { s.connection.journal(JournalExec, st, err) }()
+
 	if s.closed {
 		return nil, errClosed
 	}
 
-	fResp := Catcher.FindResponse(s.q, args)
+	if err := s.connection.catcher().checkReadOnly(s.connection.currTx, s.command); err != nil {
+		return nil, err
+	}
+
+	fResp := s.connection.catcher().findResponse(st)
+
+	// Emulate time query takes to be able to cancel it
+	clock := s.connection.catcher().clock()
+	if err := waitFor(ctx, clock, fResp.delay(clock)); err != nil {
+		return nil, err
+	}
 
 	// To emulate any exception during query which returns rows
 	if fResp.Exceptions != nil && fResp.Exceptions.HookExecBadConnection != nil && fResp.Exceptions.HookExecBadConnection() {
@@ -74,7 +121,25 @@ func (s *FakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (d
 	}
 
 	if fResp.Callback != nil {
-		fResp.Callback(s.q, args)
+		fResp.Callback(st.queryFor(fResp), args)
+	}
+	if fResp.TxCallback != nil {
+		fResp.TxCallback(st.queryFor(fResp), args, s.connection.currTx)
+	}
+
+	if st.spCmd != "" {
+		tx := s.connection.currTx
+		if tx == nil {
+			return nil, fmt.Errorf("fake_db_driver: %s can be used only in transaction", st.spCmd)
+		}
+		err := tx.execSavepoint(st.spCmd, st.spName)
+		if st.spCmd == spRollback && st.exec != nil {
+			tx.record(st.exec)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return driver.ResultNoRows, nil
 	}
 
 	switch s.command {
@@ -90,7 +155,8 @@ func (s *FakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (d
 	case "DELETE":
 		return driver.RowsAffected(fResp.RowsAffected), nil
 	}
-	return nil, fmt.Errorf("unimplemented statement Exec command type of %q", s.command)
+	// Any other statement (DDL, CALL, SET, CTE etc.) replies with values of the mock
+	return NewFakeResult(fResp.LastInsertID, fResp.RowsAffected), nil
 }
 
 // Query executes a query that may return rows, such as a
@@ -103,21 +169,25 @@ func (s *FakeStmt) Query(args []driver.Value) (driver.Rows, error) {
 
 // QueryContext executes a query that may return rows, such as a
 // SELECT.
-func (s *FakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+func (s *FakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (_ driver.Rows, err error) {
+	st := s.statement(args)
+	defer func() { s.connection.journal(JournalQuery, st, err) }()
 
 	if s.closed {
 		return nil, errClosed
 	}
 
-	if len(args) > 0 {
-		// Replace all "?" to "%v" and replace them with the values after
-		for i := 0; i < len(args); i++ {
-			s.q = strings.Replace(s.q, "?", "%v", 1)
-			s.q = fmt.Sprintf(s.q, args[i].Value)
-		}
+	if err := s.connection.catcher().checkReadOnly(s.connection.currTx, s.command); err != nil {
+		return nil, err
 	}
 
-	fResp := Catcher.FindResponse(s.q, args)
+	fResp := s.connection.catcher().findResponse(st)
+
+	// Emulate time query takes to be able to cancel it
+	clock := s.connection.catcher().clock()
+	if err := waitFor(ctx, clock, fResp.delay(clock)); err != nil {
+		return nil, err
+	}
 
 	if fResp.Exceptions != nil && fResp.Exceptions.HookQueryBadConnection != nil && fResp.Exceptions.HookQueryBadConnection() {
 		return nil, driver.ErrBadConn
@@ -127,43 +197,49 @@ func (s *FakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (
 		return nil, fResp.Error
 	}
 
-	resultRows := make([][]*row, 0, 1)
-	columnNames := make([]string, 0, 1)
-	columnTypes := make([][]string, 0, 1)
-	rows := []*row{}
-
-	// Check if we have such query in the map
-	colIndexes := make(map[string]int)
+	resultSets := fResp.resultSets()
+	resultRows := make([][]*row, 0, len(resultSets))
+	columnNames := make([][]string, 0, len(resultSets))
+	columnTypes := make([][]*ColumnType, 0, len(resultSets))
 
-	// Collecting column names from first record
-	if len(fResp.Response) > 0 {
-		for colName := range fResp.Response[0] {
-			colIndexes[colName] = len(columnNames)
-			columnNames = append(columnNames, colName)
-		}
-	}
+	for _, set := range resultSets {
+		// Columns are either declared explicitly or taken from first record in stable order
+		names := set.columnNames()
+		rows := []*row{}
 
-	// Extracting values from result according columns
-	for _, record := range fResp.Response {
-		oneRow := &row{cols: make([]interface{}, len(columnNames))}
-		for _, col := range columnNames {
-			oneRow.cols[colIndexes[col]] = record[col]
+		// Extracting values from result according columns
+		for _, record := range set.Response {
+			oneRow := &row{cols: make([]interface{}, len(names))}
+			for i, col := range names {
+				oneRow.cols[i] = record[col]
+			}
+			rows = append(rows, oneRow)
 		}
-		rows = append(rows, oneRow)
+		resultRows = append(resultRows, rows)
+		columnNames = append(columnNames, names)
+		columnTypes = append(columnTypes, fResp.columnTypesOf(names))
 	}
-	resultRows = append(resultRows, rows)
 
 	cursor := &RowsCursor{
 		posRow:  -1,
 		rows:    resultRows,
 		cols:    columnNames,
-		colType: columnTypes, // TODO: implement support of that
+		colType: columnTypes,
 		errPos:  -1,
 		closed:  false,
 	}
 
+	// To emulate error in the middle of reading rows
+	if fResp.RowError != nil {
+		cursor.errPos = fResp.RowErrorAfter
+		cursor.err = fResp.RowError
+	}
+
 	if fResp.Callback != nil {
-		fResp.Callback(s.q, args)
+		fResp.Callback(st.queryFor(fResp), args)
+	}
+	if fResp.TxCallback != nil {
+		fResp.TxCallback(st.queryFor(fResp), args, s.connection.currTx)
 	}
 
 	return cursor, nil
@@ -173,32 +249,3 @@ func (s *FakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (
 func (s *FakeStmt) NumInput() int {
 	return s.placeholders
 }
-
-// FakeTx implements Tx interface
-type FakeTx struct {
-	c *FakeConn
-}
-
-// HookBadCommit is a hook to simulate broken connections
-var HookBadCommit func() bool
-
-// Commit commits the transaction
-func (tx *FakeTx) Commit() error {
-	tx.c.currTx = nil
-	if HookBadCommit != nil && HookBadCommit() {
-		return driver.ErrBadConn
-	}
-	return nil
-}
-
-// HookBadRollback is a hook to simulate broken connections
-var HookBadRollback func() bool
-
-// Rollback rollbacks the transaction
-func (tx *FakeTx) Rollback() error {
-	tx.c.currTx = nil
-	if HookBadRollback != nil && HookBadRollback() {
-		return driver.ErrBadConn
-	}
-	return nil
-}
@@ -0,0 +1,281 @@
+package gomocket
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is a kind of SQL token
+type tokenKind int
+
+const (
+	tokenWord   tokenKind = iota // Keyword or not quoted identifier
+	tokenQuoted                  // Quoted identifier
+	tokenString                  // String literal
+	tokenNumber                  // Numeric literal
+	tokenSymbol                  // Operators and punctuation
+	tokenParam                   // Placeholder of argument: ?, ?1, $1, :name or @name
+)
+
+// sqlToken is a lexical unit of SQL query
+type sqlToken struct {
+	kind tokenKind
+	text string // Raw text of token including quotes
+	pos  int    // Position of the first rune of token in query
+}
+
+// is checks if token is word equal to keyword ignoring case
+func (t sqlToken) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// name returns identifier without quotes
+func (t sqlToken) name() string {
+	if t.kind == tokenQuoted {
+		return t.text[1 : len(t.text)-1]
+	}
+	return t.text
+}
+
+// tokenize splits SQL query into tokens skipping whitespace and comments.
+// It is not a full SQL parser, it knows just enough to find statement type and placeholders
+func tokenize(query string) []sqlToken {
+	return tokenizeWith(query, false)
+}
+
+// tokenizeWith splits SQL query into tokens. Backslash escapes quotes in all string literals
+// if enabled, like in MySQL, otherwise only in Postgres E'...' strings
+func tokenizeWith(query string, backslash bool) []sqlToken {
+	var tokens []sqlToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '-' && next(runes, i) == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			continue
+		case r == '/' && next(runes, i) == '*':
+			// Block comments could be nested in some dialects
+			depth := 0
+			for i < len(runes) {
+				if runes[i] == '/' && next(runes, i) == '*' {
+					depth++
+					i += 2
+				} else if runes[i] == '*' && next(runes, i) == '/' {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			continue
+		case r == '\'':
+			i = skipQuoted(runes, i, '\'', backslash)
+			tokens = append(tokens, sqlToken{tokenString, string(runes[start:i]), start})
+		case (r == 'E' || r == 'e') && next(runes, i) == '\'':
+			// Postgres string with backslash escapes E'...'
+			i = skipQuoted(runes, i+1, '\'', true)
+			tokens = append(tokens, sqlToken{tokenString, string(runes[start:i]), start})
+		case r == '"' || r == '`':
+			i = skipQuoted(runes, i, r, false)
+			tokens = append(tokens, sqlToken{tokenQuoted, string(runes[start:i]), start})
+		case r == '$' && !unicode.IsDigit(next(runes, i)) && dollarTag(runes, i) != "":
+			// Postgres dollar-quoted string $tag$...$tag$
+			tag := dollarTag(runes, i)
+			i += len([]rune(tag))
+			end := strings.Index(string(runes[i:]), tag)
+			if end < 0 {
+				i = len(runes)
+			} else {
+				i += len([]rune(string(runes[i:])[:end])) + len([]rune(tag))
+			}
+			tokens = append(tokens, sqlToken{tokenString, string(runes[start:i]), start})
+		case r == '?' && next(runes, i) != '|' && next(runes, i) != '&':
+			// ? or ?NNN, but not Postgres JSON operators ?| and ?&
+			for i++; i < len(runes) && unicode.IsDigit(runes[i]); i++ {
+			}
+			tokens = append(tokens, sqlToken{tokenParam, string(runes[start:i]), start})
+		case r == '$' && unicode.IsDigit(next(runes, i)):
+			for i++; i < len(runes) && unicode.IsDigit(runes[i]); i++ {
+			}
+			tokens = append(tokens, sqlToken{tokenParam, string(runes[start:i]), start})
+		case (r == ':' || r == '@') && next(runes, i) == r:
+			// Postgres cast :: or system variable @@name
+			for i += 2; i < len(runes) && isWordRune(runes[i]); i++ {
+			}
+			tokens = append(tokens, sqlToken{tokenSymbol, string(runes[start:i]), start})
+		case (r == ':' || r == '@') && (unicode.IsLetter(next(runes, i)) || next(runes, i) == '_' || (r == ':' && unicode.IsDigit(next(runes, i)))):
+			for i++; i < len(runes) && isWordRune(runes[i]) && runes[i] != '$'; i++ {
+			}
+			tokens = append(tokens, sqlToken{tokenParam, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{tokenWord, string(runes[start:i]), start})
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{tokenNumber, string(runes[start:i]), start})
+		default:
+			i++
+			tokens = append(tokens, sqlToken{tokenSymbol, string(r), start})
+		}
+	}
+	return tokens
+}
+
+// next returns rune after position or zero if there is no such
+func next(runes []rune, i int) rune {
+	if i+1 < len(runes) {
+		return runes[i+1]
+	}
+	return 0
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
+// skipQuoted returns position after closing quote. Doubled quote is an escaped one,
+// backslash escapes the next rune only if enabled
+func skipQuoted(runes []rune, i int, quote rune, backslash bool) int {
+	for i++; i < len(runes); i++ {
+		if runes[i] == '\\' && backslash {
+			i++
+			continue
+		}
+		if runes[i] == quote {
+			if next(runes, i) == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return i
+}
+
+// dollarTag returns opening tag of dollar-quoted string like $$ or $body$ starting at position
+func dollarTag(runes []rune, i int) string {
+	for j := i + 1; j < len(runes); j++ {
+		if runes[j] == '$' {
+			return string(runes[i : j+1])
+		}
+		if !unicode.IsLetter(runes[j]) && !unicode.IsDigit(runes[j]) && runes[j] != '_' {
+			return ""
+		}
+	}
+	return ""
+}
+
+// countPlaceholders returns number of arguments query expects or -1 if it should not be checked.
+// Positional ? (MySQL, SQLite) are counted, for ordinal $1 and ?1 (Postgres, SQLite) the highest number is taken.
+// Named :name and @name (Oracle, SQL Server, SQLite) are bound by name, so their count is not checked
+// unless they are mixed with positional ones, like MySQL user variables
+func countPlaceholders(query string, backslash bool) int {
+	positional, ordinal, named := 0, 0, 0
+	for _, t := range tokenizeWith(query, backslash) {
+		if t.kind != tokenParam {
+			continue
+		}
+		switch {
+		case t.text == "?":
+			positional++
+		case t.text[0] == '?' || t.text[0] == '$':
+			if n, err := strconv.Atoi(t.text[1:]); err == nil && n > ordinal {
+				ordinal = n
+			}
+		default:
+			named++
+		}
+	}
+	switch {
+	case ordinal > 0:
+		return ordinal // ? in query with $1 is Postgres JSON operator
+	case positional > 0:
+		return positional
+	case named > 0:
+		return -1
+	}
+	return 0
+}
+
+// classifyCommand returns type of SQL statement in upper case, e.g. SELECT or INSERT.
+// Comments and leading parentheses are skipped, for CTE the type of main statement is returned
+func classifyCommand(query string) string {
+	tokens := tokenize(query)
+	i := 0
+	for i < len(tokens) && tokens[i].text == "(" {
+		i++
+	}
+	if i >= len(tokens) {
+		return ""
+	}
+	if tokens[i].kind != tokenWord {
+		return ""
+	}
+	if !tokens[i].is("WITH") {
+		return strings.ToUpper(tokens[i].text)
+	}
+	i++
+	if i < len(tokens) && tokens[i].is("RECURSIVE") {
+		i++
+	}
+	// Skipping definitions: name [(columns)] AS [NOT] [MATERIALIZED] (query) [, ...]
+	for i < len(tokens) {
+		i++ // name
+		if i < len(tokens) && tokens[i].text == "(" {
+			i = skipParens(tokens, i)
+		}
+		for i < len(tokens) && (tokens[i].is("AS") || tokens[i].is("NOT") || tokens[i].is("MATERIALIZED")) {
+			i++
+		}
+		if i < len(tokens) && tokens[i].text == "(" {
+			i = skipParens(tokens, i)
+		}
+		if i < len(tokens) && tokens[i].text == "," {
+			i++
+			continue
+		}
+		break
+	}
+	for i < len(tokens) && tokens[i].text == "(" {
+		i++
+	}
+	if i >= len(tokens) || tokens[i].kind != tokenWord {
+		return "WITH"
+	}
+	return strings.ToUpper(tokens[i].text)
+}
+
+// skipParens returns position after parenthesis matching the one at position
+func skipParens(tokens []sqlToken, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		if tokens[i].kind != tokenSymbol {
+			continue
+		}
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
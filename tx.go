@@ -0,0 +1,276 @@
+package gomocket
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	txActive = iota
+	txCommitted
+	txRolledBack
+)
+
+// lastTxID is used to generate unique transaction IDs
+var lastTxID int64
+
+// FakeTx implements Tx interface
+type FakeTx struct {
+	c          *FakeConn
+	id         int64
+	isolation  driver.IsolationLevel
+	readOnly   bool
+	state      int          // One of txActive, txCommitted or txRolledBack
+	executions []*execution // Mocks triggered inside of transaction
+	savepoints []savepoint  // Stack of active savepoints
+	mu         sync.Mutex
+}
+
+// execution is a single trigger of mock inside or outside of transaction
+type execution struct {
+	tx           *FakeTx // nil when executed outside of transaction
+	rolledBackTo string  // Savepoint execution was rolled back to
+}
+
+// savepoint marks position in list of transaction executions
+type savepoint struct {
+	name string
+	mark int
+}
+
+func newFakeTx(c *FakeConn, opts driver.TxOptions) *FakeTx {
+	return &FakeTx{c: c, id: atomic.AddInt64(&lastTxID, 1), isolation: opts.Isolation, readOnly: opts.ReadOnly}
+}
+
+// ID returns unique identifier of transaction
+func (tx *FakeTx) ID() int64 {
+	return tx.id
+}
+
+// Isolation returns isolation level transaction was started with
+func (tx *FakeTx) Isolation() sql.IsolationLevel {
+	return sql.IsolationLevel(tx.isolation)
+}
+
+// ReadOnly reports if transaction was started as read-only
+func (tx *FakeTx) ReadOnly() bool {
+	return tx.readOnly
+}
+
+// record remembers that mock was triggered inside transaction
+func (tx *FakeTx) record(e *execution) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.executions = append(tx.executions, e)
+}
+
+func (tx *FakeTx) finish(state int) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.state = state
+}
+
+// status describes state of execution inside of transaction for reports
+func (tx *FakeTx) status(e *execution) string {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if e.rolledBackTo != "" {
+		return "rolled back to savepoint " + e.rolledBackTo
+	}
+	switch tx.state {
+	case txCommitted:
+		return "committed"
+	case txRolledBack:
+		return "rolled back"
+	}
+	return "not finished"
+}
+
+// Savepoints returns names of active savepoints from the oldest one
+func (tx *FakeTx) Savepoints() []string {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	names := make([]string, len(tx.savepoints))
+	for i, sp := range tx.savepoints {
+		names[i] = sp.name
+	}
+	return names
+}
+
+// findSavepoint returns index of the latest savepoint with provided name. Must be called under lock
+func (tx *FakeTx) findSavepoint(name string) (int, error) {
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if tx.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("fake_db_driver: savepoint %q does not exist", name)
+}
+
+// execSavepoint applies SAVEPOINT, ROLLBACK TO SAVEPOINT or RELEASE SAVEPOINT statement
+func (tx *FakeTx) execSavepoint(command, name string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if command == spCreate {
+		tx.savepoints = append(tx.savepoints, savepoint{name: name, mark: len(tx.executions)})
+		return nil
+	}
+	i, err := tx.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	if command == spRelease {
+		tx.savepoints = tx.savepoints[:i]
+		return nil
+	}
+	// Rolled back savepoint stays active, everything done after it is discarded
+	for _, e := range tx.executions[tx.savepoints[i].mark:] {
+		if e.rolledBackTo == "" {
+			e.rolledBackTo = name
+		}
+	}
+	tx.executions = tx.executions[:tx.savepoints[i].mark]
+	tx.savepoints = tx.savepoints[:i+1]
+	return nil
+}
+
+const (
+	spCreate   = "SAVEPOINT"
+	spRollback = "ROLLBACK TO SAVEPOINT"
+	spRelease  = "RELEASE SAVEPOINT"
+)
+
+// parseSavepoint recognizes savepoint statements and returns their command and savepoint name
+func parseSavepoint(query string) (command, name string, ok bool) {
+	tokens := tokenize(query)
+	for len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	// Drops optional keywords from the beginning of tokens
+	skip := func(optional ...string) {
+		for _, o := range optional {
+			if len(tokens) > 1 && tokens[0].is(o) {
+				tokens = tokens[1:]
+			}
+		}
+	}
+	if len(tokens) < 2 {
+		return "", "", false
+	}
+	switch {
+	case tokens[0].is("SAVEPOINT"):
+		command = spCreate
+		tokens = tokens[1:]
+	case tokens[0].is("RELEASE"):
+		command = spRelease
+		tokens = tokens[1:]
+		skip("SAVEPOINT")
+	case tokens[0].is("ROLLBACK"):
+		tokens = tokens[1:]
+		skip("WORK", "TRANSACTION")
+		if !tokens[0].is("TO") {
+			return "", "", false
+		}
+		command = spRollback
+		tokens = tokens[1:]
+		skip("SAVEPOINT")
+	default:
+		return "", "", false
+	}
+	if len(tokens) != 1 || (tokens[0].kind != tokenWord && tokens[0].kind != tokenQuoted) {
+		return "", "", false
+	}
+	return command, tokens[0].name(), true
+}
+
+// HookBadCommit is a hook to simulate broken connections
+var HookBadCommit func() bool
+
+// Commit commits the transaction
+func (tx *FakeTx) Commit() (err error) {
+	st := &statement{tx: tx, start: tx.c.catcher().clock().Now()}
+	defer func() { tx.c.journal(JournalCommit, st, err) }()
+	tx.c.currTx = nil
+	if HookBadCommit != nil && HookBadCommit() {
+		tx.finish(txRolledBack)
+		return driver.ErrBadConn
+	}
+	if err := tx.c.catcher().txEvent(txCommit, tx); err != nil {
+		tx.finish(txRolledBack)
+		return err
+	}
+	tx.finish(txCommitted)
+	return nil
+}
+
+// HookBadRollback is a hook to simulate broken connections
+var HookBadRollback func() bool
+
+// Rollback rollbacks the transaction
+func (tx *FakeTx) Rollback() (err error) {
+	st := &statement{tx: tx, start: tx.c.catcher().clock().Now()}
+	defer func() { tx.c.journal(JournalRollback, st, err) }()
+	tx.c.currTx = nil
+	tx.finish(txRolledBack)
+	if HookBadRollback != nil && HookBadRollback() {
+		return driver.ErrBadConn
+	}
+	return tx.c.catcher().txEvent(txRollback, tx)
+}
+
+const (
+	txBegin    = "BEGIN"
+	txCommit   = "COMMIT"
+	txRollback = "ROLLBACK"
+)
+
+// TxExpectation represents expected Begin, Commit or Rollback of transaction
+type TxExpectation struct {
+	Command   string         // One of BEGIN, COMMIT or ROLLBACK
+	Options   *sql.TxOptions // Expected options of BEGIN, not checked if nil
+	Error     error          // Error to return instead of successful command
+	Triggered bool           // If transaction command happened
+	mu        sync.Mutex
+}
+
+// WithError sets error to be returned by transaction command
+// example: Catcher.ExpectCommit().WithError(driver.ErrBadConn)
+func (te *TxExpectation) WithError(err error) *TxExpectation {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.Error = err
+	return te
+}
+
+// WithTxOptions sets isolation level and read-only flag BEGIN is expected with
+// example: Catcher.ExpectBegin().WithTxOptions(sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true})
+func (te *TxExpectation) WithTxOptions(opts sql.TxOptions) *TxExpectation {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.Options = &opts
+	return te
+}
+
+// trigger marks expectation as met if it wasn't yet and command matches
+func (te *TxExpectation) trigger(command string, tx *FakeTx) (bool, error) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	if te.Triggered || te.Command != command {
+		return false, nil
+	}
+	te.Triggered = true
+	if te.Options != nil && (te.Options.Isolation != tx.Isolation() || te.Options.ReadOnly != tx.ReadOnly()) {
+		return true, fmt.Errorf("mock_catcher: transaction began with isolation %v and read-only %v, expected isolation %v and read-only %v",
+			tx.Isolation(), tx.ReadOnly(), te.Options.Isolation, te.Options.ReadOnly)
+	}
+	return true, te.Error
+}
+
+func (te *TxExpectation) isTriggered() bool {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	return te.Triggered
+}